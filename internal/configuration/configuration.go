@@ -3,7 +3,9 @@ package configuration
 import (
 	"fmt"
 
+	"github.com/hkionline/prompter/internal/prompts"
 	"github.com/hkionline/prompter/internal/promptsdb"
+	"github.com/hkionline/prompter/internal/templa"
 	"github.com/knadh/koanf/parsers/yaml"
 	"github.com/knadh/koanf/providers/file"
 	"github.com/knadh/koanf/providers/structs"
@@ -11,8 +13,7 @@ import (
 )
 
 var (
-	knf     = koanf.New(".") // Global active configuration instance of Koanf
-	yparser = yaml.Parser()  // Koanf's yaml parser
+	yparser = yaml.Parser() // Koanf's yaml parser
 )
 
 // ConfigurationFile is used as the root for configuration files.
@@ -27,6 +28,7 @@ type Configuration struct {
 	Transport TransportConfiguration          `yaml:"transport" koanf:"transport"`
 	LogFile   string                          `yaml:"logFile" koanf:"logFile"`
 	Storage   promptsdb.ProviderConfiguration `yaml:"storage" koanf:"storage"`
+	Prompts   prompts.Configuration           `yaml:"prompts" koanf:"prompts"`
 }
 
 type TransportConfiguration struct {
@@ -41,6 +43,9 @@ type StreamableHTTPConfiguration struct {
 // New default configuration for the service with a default configuration provider
 func New(configFilePath string) (Configuration, error) {
 
+	// Fresh Koanf instance so that previously loaded files do not leak into this one
+	knf := koanf.New(".")
+
 	// Load default configuration
 	knf.Load(structs.Provider(ConfigurationFile{GetDefault()}, "koanf"), nil)
 
@@ -67,5 +72,15 @@ func New(configFilePath string) (Configuration, error) {
 		return Configuration{}, fmt.Errorf("invalid transport type: %s. Must be 'stdio' or 'streamable_http'", kfile.Configuration.Transport.Type)
 	}
 
+	// Validate template strictness
+	if strictness := kfile.Configuration.Prompts.Templating.Strictness; !templa.ValidStrictness(strictness) {
+		return Configuration{}, fmt.Errorf("invalid template strictness: %s. Must be 'lenient', 'warn' or 'strict'", strictness)
+	}
+
+	// Validate template time zone
+	if _, err := templa.LoadLocation(kfile.Configuration.Prompts.Templating.Timezone); err != nil {
+		return Configuration{}, fmt.Errorf("invalid template timezone: %v", err)
+	}
+
 	return kfile.Configuration, nil
 }
@@ -4,6 +4,8 @@ import (
 	"os"
 	"strings"
 	"testing"
+
+	"github.com/hkionline/prompter/internal/promptsdb"
 )
 
 func TestGetDefault(t *testing.T) {
@@ -235,3 +237,180 @@ func TestSetupRejectsLegacyTopLevelHTTP(t *testing.T) {
 		t.Fatalf("Expected legacy http error, got: %v", err)
 	}
 }
+
+func TestSetupWithPromptsPageSize(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := tempDir + "/test_page_size.yaml"
+
+	configContent := `prompter:
+  transport:
+    type: "stdio"
+  prompts:
+    page_size: 25
+    max_file_size: 2048
+    completion:
+      history: true
+      history_file: "/tmp/prompter-history.json"
+      history_size: 5
+    templating:
+      strictness: "strict"
+      timezone: "Europe/Helsinki"
+      env:
+        - TEAM
+        - REGION`
+
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	config, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	if config.Prompts.PageSize != 25 {
+		t.Errorf("Expected prompts page size 25, got %d", config.Prompts.PageSize)
+	}
+
+	if config.Prompts.MaxFileSize != 2048 {
+		t.Errorf("Expected maximum file size 2048, got %d", config.Prompts.MaxFileSize)
+	}
+
+	if !config.Prompts.Completion.History || config.Prompts.Completion.HistoryFile != "/tmp/prompter-history.json" || config.Prompts.Completion.HistorySize != 5 {
+		t.Errorf("Expected completion history settings, got %+v", config.Prompts.Completion)
+	}
+
+	if config.Prompts.Templating.Strictness != "strict" {
+		t.Errorf("Expected template strictness 'strict', got '%s'", config.Prompts.Templating.Strictness)
+	}
+
+	if config.Prompts.Templating.Timezone != "Europe/Helsinki" {
+		t.Errorf("Expected template timezone 'Europe/Helsinki', got '%s'", config.Prompts.Templating.Timezone)
+	}
+
+	if len(config.Prompts.Templating.Env) != 2 || config.Prompts.Templating.Env[1] != "REGION" {
+		t.Errorf("Expected environment allowlist [TEAM REGION], got %v", config.Prompts.Templating.Env)
+	}
+}
+
+func TestSetupRejectsInvalidStrictness(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := tempDir + "/test_strictness.yaml"
+
+	configContent := `prompter:
+  transport:
+    type: "stdio"
+  prompts:
+    templating:
+      strictness: "pedantic"`
+
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	_, err := New(configPath)
+	if err == nil {
+		t.Fatalf("Expected error for invalid template strictness")
+	}
+
+	if !strings.Contains(err.Error(), "pedantic") {
+		t.Fatalf("Expected strictness error, got: %v", err)
+	}
+}
+
+func TestSetupRejectsInvalidTimezone(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := tempDir + "/test_timezone.yaml"
+
+	configContent := `prompter:
+  transport:
+    type: "stdio"
+  prompts:
+    templating:
+      timezone: "Mars/Olympus_Mons"`
+
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	_, err := New(configPath)
+	if err == nil {
+		t.Fatalf("Expected error for invalid template timezone")
+	}
+
+	if !strings.Contains(err.Error(), "timezone") {
+		t.Fatalf("Expected timezone error, got: %v", err)
+	}
+}
+
+func TestSetupWithFilesystemStorage(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := tempDir + "/test_filesystem.yaml"
+
+	configContent := `prompter:
+  transport:
+    type: "stdio"
+  storage:
+    provider: "filesystem"
+    filesystem:
+      prompts_directory: "/tmp/prompter-prompts"
+      watch: false`
+
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	config, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	if config.Storage.Filesystem.Directory != "/tmp/prompter-prompts" {
+		t.Errorf("Expected prompts directory '/tmp/prompter-prompts', got '%s'", config.Storage.Filesystem.Directory)
+	}
+
+	if config.Storage.Filesystem.Watch {
+		t.Error("Expected watching to be disabled")
+	}
+}
+
+func TestSetupWithFilesystemDirectories(t *testing.T) {
+	tempDir := t.TempDir()
+	configPath := tempDir + "/test_directories.yaml"
+
+	configContent := `prompter:
+  transport:
+    type: "stdio"
+  storage:
+    provider: "filesystem"
+    filesystem:
+      directories:
+        - path: "/srv/team-prompts"
+          precedence: 10
+        - path: "/tmp/prompter-prompts"
+          writable: true`
+
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	config, err := New(configPath)
+	if err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	expected := []promptsdb.FsDirectoryConfiguration{
+		{Path: "/srv/team-prompts", Precedence: 10},
+		{Path: "/tmp/prompter-prompts", Writable: true},
+	}
+
+	if len(config.Storage.Filesystem.Directories) != len(expected) {
+		t.Fatalf("Expected %d directories, got %v", len(expected), config.Storage.Filesystem.Directories)
+	}
+
+	for i, directory := range expected {
+		if config.Storage.Filesystem.Directories[i] != directory {
+			t.Errorf("Expected directory %+v at index %d, got %+v", directory, i, config.Storage.Filesystem.Directories[i])
+		}
+	}
+}
@@ -5,7 +5,9 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/hkionline/prompter/internal/prompts"
 	"github.com/hkionline/prompter/internal/promptsdb"
+	"github.com/hkionline/prompter/internal/templa"
 )
 
 func GetDefault() Configuration {
@@ -15,6 +17,9 @@ func GetDefault() Configuration {
 	defaultPrompterDir := "/.config/prompter"
 	defaultPrompterLogFile := "/prompter.log"
 	defaultPromptsDir := "/prompts"
+	defaultPromptsDb := "/prompts.db"
+	defaultPromptsRepo := "/prompts-repository"
+	defaultHistoryFile := "/history.json"
 
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "configuration defaults failure: %s", err)
@@ -23,6 +28,9 @@ func GetDefault() Configuration {
 
 	promptsDir := filepath.Join(homeDir, defaultPrompterDir, defaultPromptsDir)
 	logFile := filepath.Join(homeDir, defaultPrompterDir, defaultPrompterLogFile)
+	promptsDb := filepath.Join(homeDir, defaultPrompterDir, defaultPromptsDb)
+	promptsRepo := filepath.Join(homeDir, defaultPrompterDir, defaultPromptsRepo)
+	historyFile := filepath.Join(homeDir, defaultPrompterDir, defaultHistoryFile)
 
 	return Configuration{
 		Transport: TransportConfiguration{
@@ -36,6 +44,25 @@ func GetDefault() Configuration {
 			Provider: "filesystem",
 			Filesystem: promptsdb.FsProviderConfiguration{
 				Directory: promptsDir,
+				Watch:     true,
+			},
+			Sqlite: promptsdb.SqliteProviderConfiguration{
+				Path: promptsDb,
+			},
+			Git: promptsdb.GitProviderConfiguration{
+				Repository: promptsRepo,
+				Branch:     "main",
+			},
+		},
+		Prompts: prompts.Configuration{
+			PageSize:    prompts.DEFAULT_PAGE_SIZE,
+			MaxFileSize: prompts.DEFAULT_MAX_FILE_SIZE,
+			Completion: prompts.CompletionConfiguration{
+				HistoryFile: historyFile,
+				HistorySize: prompts.DEFAULT_HISTORY_SIZE,
+			},
+			Templating: templa.Configuration{
+				Strictness: templa.DEFAULT_STRICTNESS,
 			},
 		},
 	}
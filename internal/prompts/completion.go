@@ -0,0 +1,105 @@
+package prompts
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hkionline/prompter/internal/plog"
+	"github.com/hkionline/prompter/internal/promptsdb"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	MAX_COMPLETIONS        = 100            // most completion values a single completion/complete result may hold
+	REF_PROMPT             = "ref/prompt"   // completion reference to a prompt argument
+	REF_RESOURCE           = "ref/resource" // completion reference to a resource template variable
+	RESOURCE_NAME_VARIABLE = "name"         // variable of the prompt resource template
+)
+
+// CompletionConfiguration holds the settings of argument completion
+type CompletionConfiguration struct {
+	History     bool   `yaml:"history" koanf:"history"`           // whether the argument values prompts are invoked with are remembered, off by default
+	HistoryFile string `yaml:"history_file" koanf:"history_file"` // file the argument values are remembered in, empty keeps them in memory
+	HistorySize int    `yaml:"history_size" koanf:"history_size"` // number of values remembered per prompt argument
+}
+
+// HandleComplete handles the completion/complete request. Prompt arguments are completed from
+// their allowed values, or from the remembered and example values when any value is accepted.
+// The name variable of the prompt resource template is completed from the prompt names.
+func (h *PromptHandler) HandleComplete(ctx context.Context, ss *mcp.ServerSession, req *mcp.CompleteParams) (*mcp.CompleteResult, error) {
+	h.logger.Write(plog.CLIENT, "completion/complete")
+
+	if req.Ref == nil {
+		return nil, rpcError(CODE_INVALID_PARAMS, fmt.Errorf("missing completion reference"))
+	}
+
+	var candidates []string
+
+	switch req.Ref.Type {
+	case REF_PROMPT:
+
+		prompt, err := h.db.Read(req.Ref.Name)
+		if err != nil {
+			h.logger.Write(plog.SERVER, "Failed to read prompt: %s", err.Error())
+			return nil, ToRPCError(fmt.Errorf("failed to complete prompt %s: %w", req.Ref.Name, err))
+		}
+
+		argument, ok := prompt.Argument(req.Argument.Name)
+		if !ok {
+			return nil, rpcError(CODE_INVALID_PARAMS, fmt.Errorf("prompt %s has no argument %s", req.Ref.Name, req.Argument.Name))
+		}
+
+		switch {
+		case argument.Kind() == promptsdb.ARGUMENT_BOOLEAN:
+			candidates = []string{"true", "false"}
+		case len(argument.Values) > 0 && argument.Kind() != promptsdb.ARGUMENT_LIST:
+			candidates = argument.Values
+		default:
+			candidates = slices.Concat(h.history.Values(prompt.Name, argument.Name), argument.Examples)
+		}
+
+	case REF_RESOURCE:
+
+		if req.Ref.URI != RESOURCE_URI_TEMPLATE || req.Argument.Name != RESOURCE_NAME_VARIABLE {
+			break
+		}
+
+		prompts, _, err := h.db.List(promptsdb.PromptQuery{NameStartsWith: req.Argument.Value})
+		if err != nil {
+			h.logger.Write(plog.SERVER, "Error listing prompts: %s", err.Error())
+			return nil, ToRPCError(fmt.Errorf("failed to complete prompt names: %w", err))
+		}
+
+		for _, prompt := range prompts {
+			candidates = append(candidates, prompt.Name)
+		}
+	}
+
+	return &mcp.CompleteResult{Completion: completions(candidates, req.Argument.Value)}, nil
+}
+
+// completions picks the distinct candidates starting with the typed value, ignoring case,
+// and caps them to the number a completion result may hold
+func completions(candidates []string, value string) mcp.CompletionResultDetails {
+
+	values := []string{}
+	seen := map[string]bool{}
+
+	for _, candidate := range candidates {
+
+		if seen[candidate] || !strings.HasPrefix(strings.ToLower(candidate), strings.ToLower(value)) {
+			continue
+		}
+
+		seen[candidate] = true
+		values = append(values, candidate)
+	}
+
+	return mcp.CompletionResultDetails{
+		Values:  values[:min(len(values), MAX_COMPLETIONS)],
+		Total:   len(values),
+		HasMore: len(values) > MAX_COMPLETIONS,
+	}
+}
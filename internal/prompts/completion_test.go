@@ -0,0 +1,140 @@
+package prompts
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hkionline/prompter/internal/plog"
+	"github.com/hkionline/prompter/internal/promptsdb"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCompletionHandler() *PromptHandler {
+	return newCompletionHandlerWith(Configuration{Completion: CompletionConfiguration{History: true}})
+}
+
+func newCompletionHandlerWith(config Configuration) *PromptHandler {
+	db := NewMockDB([]promptsdb.Prompt{
+		{
+			Name:    "review",
+			Content: "Review this {{.language}} code {{.depth}}",
+			Arguments: []promptsdb.Argument{
+				{Name: "language", Examples: []string{"Go", "Python", "Rust"}},
+				{Name: "depth", Values: []string{"quick", "thorough"}},
+				{Name: "code"},
+				{Name: "verbose", Type: promptsdb.ARGUMENT_BOOLEAN},
+				{Name: "token", Sensitive: true},
+			},
+		},
+		{Name: "review/security"},
+		{Name: "summarize"},
+	})
+
+	return NewPromptHandler(db, config, plog.New("/tmp/test.log"))
+}
+
+func complete(t *testing.T, handler *PromptHandler, ref *mcp.CompleteReference, argument string, value string) []string {
+	t.Helper()
+
+	result, err := handler.HandleComplete(context.Background(), nil, &mcp.CompleteParams{
+		Ref:      ref,
+		Argument: mcp.CompleteParamsArgument{Name: argument, Value: value},
+	})
+	assert.NoError(t, err)
+
+	return result.Completion.Values
+}
+
+func TestHandleComplete(t *testing.T) {
+	handler := newCompletionHandler()
+	ref := &mcp.CompleteReference{Type: REF_PROMPT, Name: "review"}
+
+	assert.Equal(t, []string{"Go", "Python", "Rust"}, complete(t, handler, ref, "language", ""))
+	assert.Equal(t, []string{"Python"}, complete(t, handler, ref, "language", "py"))
+	assert.Equal(t, []string{"thorough"}, complete(t, handler, ref, "depth", "t"))
+	assert.Empty(t, complete(t, handler, ref, "code", ""))
+	assert.Equal(t, []string{"true", "false"}, complete(t, handler, ref, "verbose", ""))
+
+	// Values the prompt was invoked with come first
+	_, err := handler.HandleGet(context.Background(), nil, &mcp.GetPromptParams{
+		Name:      "review",
+		Arguments: map[string]string{"language": "Perl", "depth": "quick", "unknown": "ignored", "token": "secret"},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"Perl", "Go", "Python", "Rust"}, complete(t, handler, ref, "language", ""))
+	assert.Empty(t, handler.history.Values("review", "unknown"))
+	// Sensitive values are not remembered
+	assert.Empty(t, complete(t, handler, ref, "token", ""))
+
+	// Prompt names complete the resource template
+	resourceRef := &mcp.CompleteReference{Type: REF_RESOURCE, URI: RESOURCE_URI_TEMPLATE}
+	assert.Equal(t, []string{"review", "review/security"}, complete(t, handler, resourceRef, "name", "rev"))
+	assert.Empty(t, complete(t, handler, &mcp.CompleteReference{Type: REF_RESOURCE, URI: "file:///{path}"}, "path", ""))
+}
+
+func TestHandleCompleteWithoutHistory(t *testing.T) {
+	// The history is off unless configured
+	handler := newCompletionHandlerWith(Configuration{})
+	ref := &mcp.CompleteReference{Type: REF_PROMPT, Name: "review"}
+
+	_, err := handler.HandleGet(context.Background(), nil, &mcp.GetPromptParams{
+		Name:      "review",
+		Arguments: map[string]string{"language": "Perl"},
+	})
+	assert.NoError(t, err)
+
+	assert.Nil(t, handler.history)
+	assert.Equal(t, []string{"Go", "Python", "Rust"}, complete(t, handler, ref, "language", ""))
+}
+
+func TestHandleCompleteErrors(t *testing.T) {
+	handler := newCompletionHandler()
+
+	tests := []struct {
+		name string
+		req  *mcp.CompleteParams
+		code int64
+	}{
+		{"missing reference", &mcp.CompleteParams{}, CODE_INVALID_PARAMS},
+		{"missing prompt", &mcp.CompleteParams{Ref: &mcp.CompleteReference{Type: REF_PROMPT, Name: "missing"}}, CODE_NOT_FOUND},
+		{
+			"unknown argument",
+			&mcp.CompleteParams{Ref: &mcp.CompleteReference{Type: REF_PROMPT, Name: "review"}, Argument: mcp.CompleteParamsArgument{Name: "unknown"}},
+			CODE_INVALID_PARAMS,
+		},
+	}
+
+	for _, test := range tests {
+		_, err := handler.HandleComplete(context.Background(), nil, test.req)
+		assert.Error(t, err, test.name)
+		assert.Equal(t, test.code, errorCode(err), test.name)
+	}
+}
+
+func TestHandleGetAllowedValues(t *testing.T) {
+	handler := newCompletionHandler()
+
+	_, err := handler.HandleGet(context.Background(), nil, &mcp.GetPromptParams{
+		Name:      "review",
+		Arguments: map[string]string{"depth": "shallow"},
+	})
+	assert.Error(t, err)
+	assert.Equal(t, int64(CODE_INVALID_PARAMS), errorCode(err))
+	assert.Contains(t, err.Error(), "argument depth: enum: shallow does not equal any of: [quick thorough]")
+}
+
+func TestCompletionsCap(t *testing.T) {
+	candidates := []string{}
+	for i := range MAX_COMPLETIONS + 5 {
+		candidates = append(candidates, fmt.Sprintf("value%d", i))
+	}
+
+	result := completions(candidates, "VALUE")
+
+	assert.Len(t, result.Values, MAX_COMPLETIONS)
+	assert.Equal(t, MAX_COMPLETIONS+5, result.Total)
+	assert.True(t, result.HasMore)
+}
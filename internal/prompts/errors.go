@@ -0,0 +1,75 @@
+package prompts
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+
+	"github.com/hkionline/prompter/internal/promptsdb"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// JSON-RPC error codes sent to clients, the codes between -32000 and -32099
+// are left for servers to define
+const (
+	CODE_NOT_FOUND      = mcp.CodeResourceNotFound // no prompt with the requested name
+	CODE_ALREADY_EXISTS = -32010                   // a prompt with the name exists already
+	CODE_READ_ONLY      = -32011                   // the prompt can not be changed
+	CODE_CONFLICT       = -32012                   // the prompt was changed since the client read it
+	CODE_TEMPLATE_ERROR = -32013                   // the template of the prompt does not parse or render
+	CODE_INVALID_PARAMS = -32602                   // the request or the prompt in it is not valid
+	CODE_INTERNAL_ERROR = -32603                   // the storage failed
+)
+
+// ToRPCError gives the error the JSON-RPC error code matching its cause,
+// the message of the error is kept as is
+func ToRPCError(err error) error {
+
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, promptsdb.ErrNotFound):
+		return rpcError(CODE_NOT_FOUND, err)
+	case errors.Is(err, promptsdb.ErrAlreadyExists):
+		return rpcError(CODE_ALREADY_EXISTS, err)
+	case errors.Is(err, promptsdb.ErrReadOnly):
+		return rpcError(CODE_READ_ONLY, err)
+	case errors.Is(err, promptsdb.ErrConflict):
+		return rpcError(CODE_CONFLICT, err)
+	case errors.Is(err, promptsdb.ErrInvalidPrompt):
+		return rpcError(CODE_INVALID_PARAMS, err)
+	default:
+		return rpcError(CODE_INTERNAL_ERROR, err)
+	}
+}
+
+// rpcError turns the error into a JSON-RPC error with the given code. The SDK keeps
+// its wire error type internal and hands it out only through mcp.ResourceNotFoundError,
+// so a fresh one is taken from there and given the code and the message. Should a new
+// SDK version change the type, the error is returned as is without a code and
+// TestErrorCodesOnTheWire in the server package fails.
+func rpcError(code int64, err error) error {
+
+	wireErr := mcp.ResourceNotFoundError("")
+	wire := reflect.ValueOf(wireErr)
+
+	if wire.Kind() != reflect.Pointer || wire.Elem().Kind() != reflect.Struct {
+		return err
+	}
+
+	codeField := wire.Elem().FieldByName("Code")
+	messageField := wire.Elem().FieldByName("Message")
+	dataField := wire.Elem().FieldByName("Data")
+
+	if !codeField.CanSet() || !messageField.CanSet() || !dataField.CanSet() ||
+		codeField.Kind() != reflect.Int64 || messageField.Kind() != reflect.String || dataField.Type() != reflect.TypeFor[json.RawMessage]() {
+		// The SDK changed, clients still get the message
+		return err
+	}
+
+	codeField.SetInt(code)
+	messageField.SetString(err.Error())
+	dataField.SetBytes(nil)
+
+	return wireErr
+}
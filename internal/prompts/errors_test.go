@@ -0,0 +1,77 @@
+package prompts
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/hkionline/prompter/internal/plog"
+	"github.com/hkionline/prompter/internal/promptsdb"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+// errorCode reads the JSON-RPC error code the client would receive
+func errorCode(err error) int64 {
+	wire := reflect.ValueOf(err)
+	if wire.Kind() != reflect.Pointer || wire.Elem().Kind() != reflect.Struct {
+		return 0
+	}
+	code := wire.Elem().FieldByName("Code")
+	if code.Kind() != reflect.Int64 {
+		return 0
+	}
+	return code.Int()
+}
+
+func TestToRPCError(t *testing.T) {
+	tests := []struct {
+		err  error
+		code int64
+	}{
+		{promptsdb.ErrNotFound, CODE_NOT_FOUND},
+		{&promptsdb.PromptsDBError{Op: "create", PromptId: "taken", Err: promptsdb.ErrAlreadyExists}, CODE_ALREADY_EXISTS},
+		{&promptsdb.PromptsDBError{Op: "update", PromptId: "team", Err: fmt.Errorf("%w: shared", promptsdb.ErrReadOnly)}, CODE_READ_ONLY},
+		{&promptsdb.PromptsDBError{Op: "update", PromptId: "stale", Err: promptsdb.ErrConflict}, CODE_CONFLICT},
+		{fmt.Errorf("tool: %w", promptsdb.ErrInvalidPrompt), CODE_INVALID_PARAMS},
+		{assert.AnError, CODE_INTERNAL_ERROR},
+	}
+
+	for _, test := range tests {
+		rpcErr := ToRPCError(test.err)
+
+		assert.Equal(t, test.code, errorCode(rpcErr), "code of %v", test.err)
+		assert.Equal(t, test.err.Error(), rpcErr.Error())
+	}
+
+	assert.NoError(t, ToRPCError(nil))
+}
+
+func TestHandleGetErrorCodes(t *testing.T) {
+	db := NewMockDB([]promptsdb.Prompt{
+		{Name: "review", Content: "{{.code}}", Arguments: []promptsdb.Argument{{Name: "code", Required: true}}},
+	})
+	logger := plog.New("/tmp/test.log")
+	handler := NewPromptHandler(db, Configuration{}, logger)
+
+	tests := []struct {
+		req  *mcp.GetPromptParams
+		code int64
+	}{
+		{&mcp.GetPromptParams{Name: ""}, CODE_INVALID_PARAMS},
+		{&mcp.GetPromptParams{Name: "nonexistent"}, CODE_NOT_FOUND},
+		{&mcp.GetPromptParams{Name: "error"}, CODE_INTERNAL_ERROR},
+		{&mcp.GetPromptParams{Name: "review"}, CODE_INVALID_PARAMS},
+	}
+
+	for _, test := range tests {
+		_, err := handler.HandleGet(context.Background(), nil, test.req)
+
+		assert.Error(t, err)
+		assert.Equal(t, test.code, errorCode(err), "code for prompt %q", test.req.Name)
+	}
+
+	_, err := handler.HandleList(context.Background(), nil, &mcp.ListPromptsParams{Cursor: "not a cursor"})
+	assert.Equal(t, int64(CODE_INVALID_PARAMS), errorCode(err))
+}
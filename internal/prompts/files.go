@@ -0,0 +1,102 @@
+package prompts
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/hkionline/prompter/internal/promptsdb"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// fileContent reads a file referenced by a prompt message. Images are sent as image
+// content and other files as embedded resources, text files as text and the rest as blobs.
+// Files are read relative to the directory the prompt was loaded from and can not
+// reach outside of it, not even through symbolic links.
+func (h *PromptHandler) fileContent(prompt promptsdb.Prompt, file string) (mcp.Content, error) {
+
+	if prompt.Source == "" {
+		return nil, fmt.Errorf("prompt %s has no directory to read %s from", prompt.Name, file)
+	}
+
+	root, err := os.OpenRoot(prompt.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	defer root.Close()
+
+	f, err := root.Open(filepath.FromSlash(file))
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", file)
+	}
+
+	// The size is checked while reading as well, the file may grow after Stat
+	data, err := io.ReadAll(io.LimitReader(f, h.maxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Size() > h.maxFileSize || int64(len(data)) > h.maxFileSize {
+		return nil, fmt.Errorf("%s is larger than the maximum file size of %d bytes", file, h.maxFileSize)
+	}
+
+	mimeType := mimeTypeOf(file, data)
+
+	if strings.HasPrefix(mimeType, "image/") {
+		return &mcp.ImageContent{Data: data, MIMEType: mimeType}, nil
+	}
+
+	absolute, err := filepath.Abs(filepath.Join(prompt.Source, filepath.FromSlash(file)))
+	if err != nil {
+		return nil, err
+	}
+
+	resource := &mcp.ResourceContents{
+		URI:      (&url.URL{Scheme: "file", Path: filepath.ToSlash(absolute)}).String(),
+		MIMEType: mimeType,
+	}
+
+	if utf8.Valid(data) {
+		resource.Text = string(data)
+	} else {
+		resource.Blob = data
+	}
+
+	return &mcp.EmbeddedResource{Resource: resource}, nil
+}
+
+// mimeTypeOf detects the media type of the file from its extension and
+// from its contents when the extension is not known
+func mimeTypeOf(file string, data []byte) string {
+
+	mimeType := mime.TypeByExtension(filepath.Ext(file))
+
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+
+	// Parameters such as the charset are left out
+	if mediaType, _, err := mime.ParseMediaType(mimeType); err == nil {
+		return mediaType
+	}
+
+	return mimeType
+}
@@ -0,0 +1,110 @@
+package prompts
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hkionline/prompter/internal/plog"
+	"github.com/hkionline/prompter/internal/promptsdb"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+// pngHeader is enough of a PNG file for its contents to be detected as an image
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func newFilesHandler(t *testing.T, messages []promptsdb.Message, config Configuration) (*PromptHandler, string) {
+	t.Helper()
+
+	promptsDir := t.TempDir()
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(promptsDir, "docs"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(promptsDir, "docs", "style.txt"), []byte("Use tabs."), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(promptsDir, "diagram.png"), pngHeader, 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(promptsDir, "data.bin"), []byte{0xff, 0xfe, 0x00}, 0644))
+
+	db := NewMockDB([]promptsdb.Prompt{{
+		Name:     "review",
+		Messages: messages,
+		Content:  "Review the code",
+		Source:   promptsDir,
+	}})
+
+	return NewPromptHandler(db, config, plog.New("/tmp/test.log")), promptsDir
+}
+
+func TestHandleGetFiles(t *testing.T) {
+	handler, promptsDir := newFilesHandler(t, []promptsdb.Message{
+		{Role: promptsdb.ROLE_USER, File: "docs/style.txt"},
+		{Role: promptsdb.ROLE_USER, File: "diagram.png"},
+		{Role: promptsdb.ROLE_USER, File: "data.bin"},
+	}, Configuration{})
+
+	resp, err := handler.HandleGet(context.Background(), nil, &mcp.GetPromptParams{Name: "review"})
+
+	assert.NoError(t, err)
+	assert.Len(t, resp.Messages, 4)
+
+	if resource, ok := resp.Messages[0].Content.(*mcp.EmbeddedResource); ok {
+		assert.Equal(t, "Use tabs.", resource.Resource.Text)
+		assert.Equal(t, "text/plain", resource.Resource.MIMEType)
+		assert.Equal(t, "file://"+filepath.ToSlash(filepath.Join(promptsDir, "docs", "style.txt")), resource.Resource.URI)
+	} else {
+		t.Errorf("Expected EmbeddedResource type, got %T", resp.Messages[0].Content)
+	}
+
+	if image, ok := resp.Messages[1].Content.(*mcp.ImageContent); ok {
+		assert.Equal(t, "image/png", image.MIMEType)
+		assert.Equal(t, pngHeader, image.Data)
+	} else {
+		t.Errorf("Expected ImageContent type, got %T", resp.Messages[1].Content)
+	}
+
+	if resource, ok := resp.Messages[2].Content.(*mcp.EmbeddedResource); ok {
+		assert.Empty(t, resource.Resource.Text)
+		assert.Equal(t, []byte{0xff, 0xfe, 0x00}, resource.Resource.Blob)
+	} else {
+		t.Errorf("Expected EmbeddedResource type, got %T", resp.Messages[2].Content)
+	}
+
+	if textContent, ok := resp.Messages[3].Content.(*mcp.TextContent); ok {
+		assert.Equal(t, "Review the code", textContent.Text)
+	} else {
+		t.Errorf("Expected TextContent type, got %T", resp.Messages[3].Content)
+	}
+}
+
+func TestHandleGetFileErrors(t *testing.T) {
+	outsideDir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(outsideDir, "secret.txt"), []byte("secret"), 0644))
+
+	tests := []struct {
+		name   string
+		file   string
+		config Configuration
+	}{
+		{"missing file", "missing.txt", Configuration{}},
+		{"directory", "docs", Configuration{}},
+		{"too large", "docs/style.txt", Configuration{MaxFileSize: 4}},
+		{"symbolic link out of the directory", "link.txt", Configuration{}},
+	}
+
+	for _, test := range tests {
+		handler, promptsDir := newFilesHandler(t, []promptsdb.Message{{File: test.file}}, test.config)
+		assert.NoError(t, os.Symlink(filepath.Join(outsideDir, "secret.txt"), filepath.Join(promptsDir, "link.txt")))
+
+		resp, err := handler.HandleGet(context.Background(), nil, &mcp.GetPromptParams{Name: "review"})
+
+		assert.Error(t, err, test.name)
+		assert.Nil(t, resp, test.name)
+	}
+
+	// Prompts not loaded from a directory can not send files
+	db := NewMockDB([]promptsdb.Prompt{{Name: "stored", Messages: []promptsdb.Message{{File: "style.txt"}}}})
+	handler := NewPromptHandler(db, Configuration{}, plog.New("/tmp/test.log"))
+
+	_, err := handler.HandleGet(context.Background(), nil, &mcp.GetPromptParams{Name: "stored"})
+	assert.Error(t, err)
+}
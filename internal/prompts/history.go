@@ -0,0 +1,149 @@
+package prompts
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	DEFAULT_HISTORY_SIZE     = 20  // number of earlier values remembered per prompt argument when not configured
+	MAX_HISTORY_VALUE_LENGTH = 200 // longest value in characters remembered, longer ones such as pasted code are not offered as completions
+)
+
+// History remembers the latest argument values prompts were invoked with, the values are
+// offered as completions. The values are kept in a JSON file when the history has a path.
+// A nil History remembers nothing.
+type History struct {
+	mu     sync.Mutex
+	path   string
+	size   int
+	values map[string]map[string][]string // prompt name -> argument name -> values, latest first
+}
+
+// NewHistory creates a history kept in the given file, an empty path keeps the history in memory only
+func NewHistory(path string, size int) (*History, error) {
+
+	if size <= 0 {
+		size = DEFAULT_HISTORY_SIZE
+	}
+
+	h := &History{
+		path:   path,
+		size:   size,
+		values: map[string]map[string][]string{},
+	}
+
+	if path == "" {
+		return h, nil
+	}
+
+	data, err := os.ReadFile(path)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+
+	if err != nil {
+		return h, err
+	}
+
+	if err = json.Unmarshal(data, &h.values); err != nil {
+		h.values = map[string]map[string][]string{}
+		return h, err
+	}
+
+	return h, nil
+}
+
+// Record remembers the non-empty argument values the prompt was invoked with, values longer
+// than MAX_HISTORY_VALUE_LENGTH are skipped
+func (h *History) Record(prompt string, arguments map[string]string) error {
+
+	if h == nil {
+		return nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	changed := false
+
+	for name, value := range arguments {
+
+		if value == "" || utf8.RuneCountInString(value) > MAX_HISTORY_VALUE_LENGTH {
+			continue
+		}
+
+		if h.values[prompt] == nil {
+			h.values[prompt] = map[string][]string{}
+		}
+
+		values := h.values[prompt][name]
+
+		if len(values) > 0 && values[0] == value {
+			continue
+		}
+
+		// The value moves to the front when it was used before
+		values = slices.DeleteFunc(values, func(v string) bool { return v == value })
+		values = slices.Insert(values, 0, value)
+
+		h.values[prompt][name] = values[:min(len(values), h.size)]
+		changed = true
+	}
+
+	if !changed || h.path == "" {
+		return nil
+	}
+
+	return h.save()
+}
+
+// Values returns the remembered values of the prompt argument, latest first
+func (h *History) Values(prompt string, argument string) []string {
+
+	if h == nil {
+		return nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return slices.Clone(h.values[prompt][argument])
+}
+
+// save writes the history to a temporary file which is then renamed over the history file
+func (h *History) save() error {
+
+	data, err := json.MarshalIndent(h.values, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(h.path), "."+filepath.Base(h.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), h.path)
+}
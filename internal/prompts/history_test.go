@@ -0,0 +1,53 @@
+package prompts
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistoryRecord(t *testing.T) {
+	history, err := NewHistory("", 3)
+	assert.NoError(t, err)
+
+	for _, language := range []string{"Go", "Rust", "Go", "Python", "Zig"} {
+		assert.NoError(t, history.Record("review", map[string]string{"language": language, "focus": ""}))
+	}
+
+	// Latest first, reused values move to the front and the oldest fall off
+	assert.Equal(t, []string{"Zig", "Python", "Go"}, history.Values("review", "language"))
+	assert.Empty(t, history.Values("review", "focus"))
+	assert.Empty(t, history.Values("other", "language"))
+
+	// Long values such as pasted code are not remembered
+	assert.NoError(t, history.Record("review", map[string]string{"language": strings.Repeat("x", MAX_HISTORY_VALUE_LENGTH+1)}))
+	assert.Equal(t, []string{"Zig", "Python", "Go"}, history.Values("review", "language"))
+
+	// A nil history remembers nothing
+	var disabled *History
+	assert.NoError(t, disabled.Record("review", map[string]string{"language": "Go"}))
+	assert.Empty(t, disabled.Values("review", "language"))
+}
+
+func TestHistoryFile(t *testing.T) {
+	historyFile := filepath.Join(t.TempDir(), "prompter", "history.json")
+
+	history, err := NewHistory(historyFile, 0)
+	assert.NoError(t, err)
+	assert.NoError(t, history.Record("review", map[string]string{"language": "Go"}))
+
+	// The values survive a restart
+	reloaded, err := NewHistory(historyFile, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Go"}, reloaded.Values("review", "language"))
+
+	// A broken file starts an empty history
+	assert.NoError(t, os.WriteFile(historyFile, []byte("not json"), 0644))
+
+	broken, err := NewHistory(historyFile, 0)
+	assert.Error(t, err)
+	assert.Empty(t, broken.Values("review", "language"))
+}
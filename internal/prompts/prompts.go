@@ -2,7 +2,11 @@ package prompts
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/hkionline/prompter/internal/plog"
 	"github.com/hkionline/prompter/internal/promptsdb"
@@ -10,43 +14,163 @@ import (
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
+const (
+	DEFAULT_PAGE_SIZE     = 100      // number of prompts returned per prompts/list page when not configured
+	DEFAULT_MAX_FILE_SIZE = 1 << 20  // largest file in bytes a prompt message can send when not configured
+	META_SOURCE           = "source" // _meta key telling where the storage provider loaded the prompt from
+)
+
+// Configuration holds the settings used when serving prompts to clients
+type Configuration struct {
+	PageSize    int                     `yaml:"page_size" koanf:"page_size"`         // maximum number of prompts returned in a single prompts/list page
+	MaxFileSize int64                   `yaml:"max_file_size" koanf:"max_file_size"` // largest file in bytes a prompt message can send
+	Completion  CompletionConfiguration `yaml:"completion" koanf:"completion"`       // completion of the prompt arguments
+	Templating  templa.Configuration    `yaml:"templating" koanf:"templating"`       // rendering of the prompt templates
+}
+
 // PromptHandler handles MCP prompt requests
 type PromptHandler struct {
-	db     promptsdb.Provider
-	logger *plog.Plogger
+	db          promptsdb.Provider
+	logger      *plog.Plogger
+	pageSize    int
+	maxFileSize int64
+	history     *History
+	templater   *templa.Templater
 }
 
 // NewPromptHandler creates a new PromptHandler instance
-func NewPromptHandler(db promptsdb.Provider, logger *plog.Plogger) *PromptHandler {
-	return &PromptHandler{
-		db:     db,
-		logger: logger,
+func NewPromptHandler(db promptsdb.Provider, config Configuration, logger *plog.Plogger) *PromptHandler {
+
+	pageSize := config.PageSize
+
+	if pageSize <= 0 {
+		pageSize = DEFAULT_PAGE_SIZE
+	}
+
+	maxFileSize := config.MaxFileSize
+
+	if maxFileSize <= 0 {
+		maxFileSize = DEFAULT_MAX_FILE_SIZE
+	}
+
+	// Argument values may hold code or secrets, they are only remembered when the history is turned on.
+	// A broken history file only loses the earlier values offered as completions.
+	var history *History
+	if config.Completion.History {
+		var err error
+		history, err = NewHistory(config.Completion.HistoryFile, config.Completion.HistorySize)
+		if err != nil {
+			logger.Write(plog.SERVER, "failed to load the argument history: %s", err.Error())
+		}
 	}
+
+	h := &PromptHandler{
+		db:          db,
+		logger:      logger,
+		pageSize:    pageSize,
+		maxFileSize: maxFileSize,
+		history:     history,
+	}
+
+	// Arguments missing from templates in warn mode only end up in the log
+	h.templater = templa.New(config.Templating, h.resolveInclude, func(err *templa.TemplateError) {
+		logger.Write(plog.SERVER, "Template refers to missing argument %s at %s", err.Argument, err.Error())
+	})
+
+	return h
 }
 
 // HandleList handles the prompts/list request
 func (h *PromptHandler) HandleList(ctx context.Context, ss *mcp.ServerSession, req *mcp.ListPromptsParams) (*mcp.ListPromptsResult, error) {
 	h.logger.Write(plog.CLIENT, "prompts/list")
 
-	prompts, err := h.db.List(promptsdb.PromptQuery{All: true})
+	offset := 0
+
+	if req != nil && req.Cursor != "" {
+		var err error
+		offset, err = decodeCursor(req.Cursor)
+		if err != nil {
+			h.logger.Write(plog.SERVER, "Invalid prompts/list cursor: %s", err.Error())
+			return nil, rpcError(CODE_INVALID_PARAMS, fmt.Errorf("invalid cursor: %w", err))
+		}
+	}
+
+	// Partials are only included in other prompts
+	prompts, total, err := h.db.List(promptsdb.PromptQuery{
+		NoPartials: true,
+		IndexFrom:  offset,
+		IndexTo:    offset + h.pageSize,
+	})
 	if err != nil {
 		h.logger.Write(plog.SERVER, "Error listing prompts: %s", err.Error())
-		return nil, fmt.Errorf("failed to list prompts: %w", err)
+		return nil, ToRPCError(fmt.Errorf("failed to list prompts: %w", err))
 	}
 
 	// Convert to MCP prompt format
 	mcpPrompts := make([]*mcp.Prompt, len(prompts))
 	for i, prompt := range prompts {
-		mcpPrompts[i] = &mcp.Prompt{
-			Name:        prompt.Name,
-			Title:       prompt.Title,
-			Description: prompt.Description,
-		}
+		mcpPrompts[i] = ToMCPPrompt(prompt)
 	}
 
-	return &mcp.ListPromptsResult{
+	result := &mcp.ListPromptsResult{
 		Prompts: mcpPrompts,
-	}, nil
+	}
+
+	// Only hand out a cursor when there are prompts left after this page
+	if next := offset + len(prompts); len(prompts) > 0 && next < total {
+		result.NextCursor = encodeCursor(next)
+	}
+
+	return result, nil
+}
+
+// ToMCPPrompt converts a stored prompt to the MCP prompt definition advertised to clients
+func ToMCPPrompt(prompt promptsdb.Prompt) *mcp.Prompt {
+
+	mcpPrompt := &mcp.Prompt{
+		Name:        prompt.Name,
+		Title:       prompt.Title,
+		Description: prompt.Description,
+	}
+
+	if prompt.Source != "" {
+		mcpPrompt.Meta = mcp.Meta{META_SOURCE: prompt.Source}
+	}
+
+	for _, argument := range prompt.Arguments {
+		mcpPrompt.Arguments = append(mcpPrompt.Arguments, &mcp.PromptArgument{
+			Name:        argument.Name,
+			Description: argument.Description,
+			Required:    argument.Required,
+		})
+	}
+
+	return mcpPrompt
+}
+
+// encodeCursor turns a list offset into an opaque prompts/list cursor
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset)))
+}
+
+// decodeCursor turns an opaque prompts/list cursor back into a list offset
+func decodeCursor(cursor string) (int, error) {
+
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+
+	offset, err := strconv.Atoi(string(decoded))
+	if err != nil {
+		return 0, err
+	}
+
+	if offset < 0 {
+		return 0, fmt.Errorf("negative offset %d", offset)
+	}
+
+	return offset, nil
 }
 
 // HandleGet handles the prompts/get request
@@ -55,27 +179,134 @@ func (h *PromptHandler) HandleGet(ctx context.Context, ss *mcp.ServerSession, re
 
 	if req.Name == "" {
 		h.logger.Write(plog.SERVER, "Missing prompt name")
-		return nil, fmt.Errorf("missing prompt name")
+		return nil, rpcError(CODE_INVALID_PARAMS, fmt.Errorf("missing prompt name"))
 	}
 
 	prompt, err := h.db.Read(req.Name)
 	if err != nil {
-		h.logger.Write(plog.SERVER, "Prompt not found: %s", err.Error())
-		return nil, fmt.Errorf("prompt with name %s not found: %w", req.Name, err)
+		h.logger.Write(plog.SERVER, "Failed to read prompt: %s", err.Error())
+		return nil, ToRPCError(fmt.Errorf("failed to get prompt %s: %w", req.Name, err))
 	}
 
-	// Process template if needed
-	processedContent := templa.Process(prompt.Content, req.Arguments)
+	// Refuse to render prompts without the arguments they require
+	missing := []string{}
+	for _, argument := range prompt.Arguments {
+		if argument.Required && argument.Default == nil && req.Arguments[argument.Name] == "" {
+			missing = append(missing, argument.Name)
+		}
+	}
 
-	return &mcp.GetPromptResult{
-		Description: prompt.Description,
-		Messages: []*mcp.PromptMessage{
-			{
-				Role: "user",
+	if len(missing) > 0 {
+		h.logger.Write(plog.SERVER, "Missing required arguments: %s", strings.Join(missing, ", "))
+		return nil, rpcError(CODE_INVALID_PARAMS, fmt.Errorf("prompt %s is missing required arguments: %s", req.Name, strings.Join(missing, ", ")))
+	}
+
+	// Typed arguments are coerced and checked against their schema, declared arguments which were
+	// not given get their default or render empty, also in strict mode
+	arguments, err := prompt.ResolveArguments(req.Arguments)
+	if err != nil {
+		h.logger.Write(plog.SERVER, "Invalid arguments: %s", err.Error())
+		return nil, rpcError(CODE_INVALID_PARAMS, fmt.Errorf("invalid arguments for prompt %s: %w", req.Name, err))
+	}
+
+	// Process the template of every text message, the prompt contents are the last message
+	messages := []*mcp.PromptMessage{}
+	for i, message := range prompt.Conversation() {
+
+		if message.File == "" {
+
+			// Only the contents extend the base prompt
+			extends := ""
+			if i >= len(prompt.Messages) {
+				extends = prompt.Extends
+			}
+
+			text, err := h.templater.ProcessExtending(message.Content, extends, arguments)
+			if err != nil {
+				h.logger.Write(plog.SERVER, "Failed to render prompt template: %s", err.Error())
+				return nil, rpcError(templateErrorCode(err), fmt.Errorf("failed to render %s of prompt %s: %w", templatePart(prompt, i), req.Name, err))
+			}
+
+			messages = append(messages, &mcp.PromptMessage{
+				Role: mcp.Role(message.Role),
 				Content: &mcp.TextContent{
-					Text: processedContent,
+					Text: text,
 				},
-			},
-		},
+			})
+			continue
+		}
+
+		content, err := h.fileContent(prompt, message.File)
+		if err != nil {
+			h.logger.Write(plog.SERVER, "Failed to read prompt file: %s", err.Error())
+			return nil, ToRPCError(fmt.Errorf("failed to get prompt %s: %w", req.Name, err))
+		}
+
+		messages = append(messages, &mcp.PromptMessage{
+			Role:    mcp.Role(message.Role),
+			Content: content,
+		})
+	}
+
+	// Remember the values of the declared arguments for completing them later, except the sensitive ones
+	values := map[string]string{}
+	for _, argument := range prompt.Arguments {
+		if !argument.Sensitive {
+			values[argument.Name] = req.Arguments[argument.Name]
+		}
+	}
+
+	if err := h.history.Record(prompt.Name, values); err != nil {
+		h.logger.Write(plog.SERVER, "Failed to save the argument history: %s", err.Error())
+	}
+
+	return &mcp.GetPromptResult{
+		Description: prompt.Description,
+		Messages:    messages,
+	}, nil
+}
+
+// templatePart names the part of the prompt the conversation message at the index comes
+// from, the messages of the frontmatter are numbered from 1 and the content comes last
+func templatePart(prompt promptsdb.Prompt, index int) string {
+
+	if index < len(prompt.Messages) {
+		return fmt.Sprintf("message %d", index+1)
+	}
+
+	return "content"
+}
+
+// templateErrorCode tells a missing argument, which the client can give, from a template
+// the prompt author has to fix
+func templateErrorCode(err error) int64 {
+
+	var templateErr *templa.TemplateError
+	if errors.As(err, &templateErr) && templateErr.Argument != "" {
+		return CODE_INVALID_PARAMS
+	}
+
+	return CODE_TEMPLATE_ERROR
+}
+
+// resolveInclude looks up the prompt or partial included with {{include}} or extended with
+// extends, the arguments it declares get their default or render empty unless the including
+// template gives them
+func (h *PromptHandler) resolveInclude(name string) (templa.Include, error) {
+
+	prompt, err := h.db.Read(name)
+	if err != nil {
+		return templa.Include{}, err
+	}
+
+	defaults := map[string]any{}
+	for _, argument := range prompt.Arguments {
+		defaults[argument.Name] = argument.Fallback()
+	}
+
+	return templa.Include{
+		Content:  prompt.Content,
+		Extends:  prompt.Extends,
+		Defaults: defaults,
 	}, nil
 }
@@ -21,7 +21,7 @@ func TestBackwardCompatibility(t *testing.T) {
 
 	db := NewMockDB([]promptsdb.Prompt{testPrompt})
 	logger := plog.New("/tmp/test.log")
-	handler := NewPromptHandler(db, logger)
+	handler := NewPromptHandler(db, Configuration{}, logger)
 
 	req := &mcp.GetPromptParams{
 		Name: "legacy-prompt",
@@ -53,7 +53,7 @@ func TestBackwardCompatibilityWithArguments(t *testing.T) {
 
 	db := NewMockDB([]promptsdb.Prompt{testPrompt})
 	logger := plog.New("/tmp/test.log")
-	handler := NewPromptHandler(db, logger)
+	handler := NewPromptHandler(db, Configuration{}, logger)
 
 	req := &mcp.GetPromptParams{
 		Name: "legacy-with-args",
@@ -78,7 +78,7 @@ func TestBackwardCompatibilityWithArguments(t *testing.T) {
 }
 
 func TestInvalidTemplateBackwardCompatibility(t *testing.T) {
-	// Test that prompts with invalid template syntax return original content
+	// Test that prompts with invalid template syntax fail instead of returning the unrendered content
 	testPrompt := promptsdb.Prompt{
 		Name:        "invalid-template",
 		Title:       "Invalid Template",
@@ -88,7 +88,7 @@ func TestInvalidTemplateBackwardCompatibility(t *testing.T) {
 
 	db := NewMockDB([]promptsdb.Prompt{testPrompt})
 	logger := plog.New("/tmp/test.log")
-	handler := NewPromptHandler(db, logger)
+	handler := NewPromptHandler(db, Configuration{}, logger)
 
 	req := &mcp.GetPromptParams{
 		Name: "invalid-template",
@@ -96,15 +96,9 @@ func TestInvalidTemplateBackwardCompatibility(t *testing.T) {
 
 	resp, err := handler.HandleGet(context.Background(), nil, req)
 
-	// Should not return error, but should return original content
-	assert.NoError(t, err)
-	assert.NotNil(t, resp)
-	assert.Len(t, resp.Messages, 1)
-
-	if textContent, ok := resp.Messages[0].Content.(*mcp.TextContent); ok {
-		// Should return original content when template parsing fails
-		assert.Equal(t, "This has {{invalid syntax", textContent.Text)
-	} else {
-		t.Error("Expected TextContent type")
-	}
+	// Should return an error telling where the template is broken
+	assert.Nil(t, resp)
+	assert.ErrorContains(t, err, "failed to render content of prompt invalid-template: line 1")
+	// The template is broken, not the request
+	assert.Equal(t, int64(CODE_TEMPLATE_ERROR), errorCode(err))
 }
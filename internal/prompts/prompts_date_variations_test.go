@@ -54,7 +54,7 @@ func TestDateFunctionVariations(t *testing.T) {
 
 			db := NewMockDB([]promptsdb.Prompt{testPrompt})
 			logger := plog.New("/tmp/test.log")
-			handler := NewPromptHandler(db, logger)
+			handler := NewPromptHandler(db, Configuration{}, logger)
 
 			req := &mcp.GetPromptParams{
 				Name:      testPrompt.Name,
@@ -6,6 +6,7 @@ import (
 
 	"github.com/hkionline/prompter/internal/plog"
 	"github.com/hkionline/prompter/internal/promptsdb"
+	"github.com/hkionline/prompter/internal/templa"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/stretchr/testify/assert"
 )
@@ -21,7 +22,7 @@ func TestHandleGetWithTemplate(t *testing.T) {
 
 	db := NewMockDB([]promptsdb.Prompt{testPrompt})
 	logger := plog.New("/tmp/test.log")
-	handler := NewPromptHandler(db, logger)
+	handler := NewPromptHandler(db, Configuration{}, logger)
 
 	req := &mcp.GetPromptParams{
 		Name: "test-template",
@@ -64,7 +65,7 @@ func TestHandleGetWithTemplateNoArgs(t *testing.T) {
 
 	db := NewMockDB([]promptsdb.Prompt{testPrompt})
 	logger := plog.New("/tmp/test.log")
-	handler := NewPromptHandler(db, logger)
+	handler := NewPromptHandler(db, Configuration{}, logger)
 
 	req := &mcp.GetPromptParams{
 		Name:      "test-template-no-args",
@@ -96,12 +97,12 @@ func TestHandleGetWithInvalidTemplate(t *testing.T) {
 		Name:        "test-invalid-template",
 		Title:       "Test Invalid Template",
 		Description: "Test Description",
-		Content:     "Hello {{.InvalidSyntax",
+		Content:     "Hello\n{{.InvalidSyntax",
 	}
 
 	db := NewMockDB([]promptsdb.Prompt{testPrompt})
 	logger := plog.New("/tmp/test.log")
-	handler := NewPromptHandler(db, logger)
+	handler := NewPromptHandler(db, Configuration{}, logger)
 
 	req := &mcp.GetPromptParams{
 		Name: "test-invalid-template",
@@ -109,15 +110,212 @@ func TestHandleGetWithInvalidTemplate(t *testing.T) {
 
 	resp, err := handler.HandleGet(context.Background(), nil, req)
 
-	// Should not return error, but should return original content
+	// Should return an error with the line of the broken template instead of the original content
+	assert.Nil(t, resp)
+	assert.ErrorContains(t, err, "failed to render content of prompt test-invalid-template: line 2")
+	assert.Equal(t, int64(CODE_TEMPLATE_ERROR), errorCode(err))
+}
+
+func TestHandleGetTemplateStrictness(t *testing.T) {
+	testPrompt := promptsdb.Prompt{
+		Name:      "strictness",
+		Arguments: []promptsdb.Argument{{Name: "focus"}},
+		Messages:  []promptsdb.Message{{Role: promptsdb.ROLE_USER, Content: "Review{{if .focus}} for {{.focus}}{{end}}"}},
+		Content:   "Language:\n  {{.language}}",
+	}
+
+	tests := []struct {
+		strictness string
+		expected   string
+		err        string
+	}{
+		{templa.STRICTNESS_LENIENT, "Language:\n  <no value>", ""},
+		{templa.STRICTNESS_WARN, "Language:\n  <no value>", ""},
+		{"", "Language:\n  <no value>", ""},
+		{templa.STRICTNESS_STRICT, "", `failed to render content of prompt strictness: line 2, column 5: <.language>: map has no entry for key "language"`},
+	}
+
+	for _, test := range tests {
+		db := NewMockDB([]promptsdb.Prompt{testPrompt})
+		config := Configuration{Templating: templa.Configuration{Strictness: test.strictness}}
+		handler := NewPromptHandler(db, config, plog.New("/tmp/test.log"))
+
+		resp, err := handler.HandleGet(context.Background(), nil, &mcp.GetPromptParams{Name: "strictness"})
+
+		if test.err != "" {
+			assert.Nil(t, resp, test.strictness)
+			assert.EqualError(t, err, test.err, test.strictness)
+			assert.Equal(t, int64(CODE_INVALID_PARAMS), errorCode(err), test.strictness)
+			continue
+		}
+
+		assert.NoError(t, err, test.strictness)
+		assert.Len(t, resp.Messages, 2, test.strictness)
+		// The declared argument which was not given renders empty
+		assert.Equal(t, "Review", resp.Messages[0].Content.(*mcp.TextContent).Text, test.strictness)
+		assert.Equal(t, test.expected, resp.Messages[1].Content.(*mcp.TextContent).Text, test.strictness)
+	}
+}
+
+func TestHandleGetTemplateErrorInMessage(t *testing.T) {
+	testPrompt := promptsdb.Prompt{
+		Name: "broken-message",
+		Messages: []promptsdb.Message{
+			{Role: promptsdb.ROLE_USER, Content: "Fine"},
+			{Role: promptsdb.ROLE_ASSISTANT, Content: "Broken {{unknown}}"},
+		},
+		Content: "Fine as well",
+	}
+
+	db := NewMockDB([]promptsdb.Prompt{testPrompt})
+	handler := NewPromptHandler(db, Configuration{}, plog.New("/tmp/test.log"))
+
+	resp, err := handler.HandleGet(context.Background(), nil, &mcp.GetPromptParams{Name: "broken-message"})
+
+	assert.Nil(t, resp)
+	assert.EqualError(t, err, `failed to render message 2 of prompt broken-message: line 1: function "unknown" not defined`)
+}
+
+func TestHandleGetWithInclude(t *testing.T) {
+	testPrompts := []promptsdb.Prompt{
+		{
+			Name:      "shared/style_guide",
+			Arguments: []promptsdb.Argument{{Name: "audience"}},
+			Content:   "Keep it short{{if .audience}} for {{.audience}}{{end}}.",
+			Partial:   true,
+		},
+		{
+			Name:      "review",
+			Arguments: []promptsdb.Argument{{Name: "language", Required: true}},
+			Content:   "Review the {{.language}} code. {{include \"shared/style_guide\" \"audience\" \"juniors\"}}",
+		},
+		{
+			Name:    "broken_include",
+			Content: "Review.\n{{include \"shared/missing\"}}",
+		},
+	}
+
+	db := NewMockDB(testPrompts)
+	config := Configuration{Templating: templa.Configuration{Strictness: templa.STRICTNESS_STRICT}}
+	handler := NewPromptHandler(db, config, plog.New("/tmp/test.log"))
+
+	resp, err := handler.HandleGet(context.Background(), nil, &mcp.GetPromptParams{Name: "review", Arguments: map[string]string{"language": "Go"}})
+
 	assert.NoError(t, err)
-	assert.NotNil(t, resp)
-	assert.Len(t, resp.Messages, 1)
+	assert.Equal(t, "Review the Go code. Keep it short for juniors.", resp.Messages[0].Content.(*mcp.TextContent).Text)
 
-	if textContent, ok := resp.Messages[0].Content.(*mcp.TextContent); ok {
-		// Should return original content when template parsing fails
-		assert.Equal(t, "Hello {{.InvalidSyntax", textContent.Text)
-	} else {
-		t.Error("Expected TextContent type")
+	// The declared argument of the partial renders empty in strict mode when not given
+	resp, err = handler.HandleGet(context.Background(), nil, &mcp.GetPromptParams{Name: "shared/style_guide"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Keep it short.", resp.Messages[0].Content.(*mcp.TextContent).Text)
+
+	resp, err = handler.HandleGet(context.Background(), nil, &mcp.GetPromptParams{Name: "broken_include"})
+
+	assert.Nil(t, resp)
+	assert.ErrorContains(t, err, "failed to render content of prompt broken_include: line 2, column 3: <include \"shared/missing\">: shared/missing:")
+}
+
+func TestHandleListHidesPartials(t *testing.T) {
+	testPrompts := []promptsdb.Prompt{
+		{Name: "review", Content: "{{include \"shared/style_guide\"}}"},
+		{Name: "shared/style_guide", Content: "Keep it short.", Partial: true},
+	}
+
+	db := NewMockDB(testPrompts)
+	handler := NewPromptHandler(db, Configuration{}, plog.New("/tmp/test.log"))
+
+	resp, err := handler.HandleList(context.Background(), nil, &mcp.ListPromptsParams{})
+
+	assert.NoError(t, err)
+	assert.Len(t, resp.Prompts, 1)
+	assert.Equal(t, "review", resp.Prompts[0].Name)
+}
+
+func TestHandleGetExtends(t *testing.T) {
+	testPrompts := []promptsdb.Prompt{
+		{
+			Name:      "base_review",
+			Arguments: []promptsdb.Argument{{Name: "team"}},
+			Content:   "Review the {{.language}} code.\n{{block \"focus\" .}}Look for bugs.{{end}}{{if .team}} Owned by {{.team}}.{{end}}",
+			Partial:   true,
+		},
+		{
+			Name:      "security_review",
+			Arguments: []promptsdb.Argument{{Name: "language", Required: true}},
+			Messages:  []promptsdb.Message{{Content: "Context for {{.language}}"}},
+			Extends:   "base_review",
+			Content:   `{{define "focus"}}Look for injections.{{end}}`,
+		},
+		{
+			Name:    "broken_base",
+			Extends: "missing_base",
+		},
+	}
+
+	db := NewMockDB(testPrompts)
+	config := Configuration{Templating: templa.Configuration{Strictness: templa.STRICTNESS_STRICT}}
+	handler := NewPromptHandler(db, config, plog.New("/tmp/test.log"))
+
+	resp, err := handler.HandleGet(context.Background(), nil, &mcp.GetPromptParams{Name: "security_review", Arguments: map[string]string{"language": "Go"}})
+
+	assert.NoError(t, err)
+	assert.Len(t, resp.Messages, 2)
+	// Messages do not extend the base, the contents do
+	assert.Equal(t, "Context for Go", resp.Messages[0].Content.(*mcp.TextContent).Text)
+	assert.Equal(t, "Review the Go code.\nLook for injections.", resp.Messages[1].Content.(*mcp.TextContent).Text)
+
+	resp, err = handler.HandleGet(context.Background(), nil, &mcp.GetPromptParams{Name: "broken_base"})
+
+	assert.Nil(t, resp)
+	assert.ErrorContains(t, err, "failed to render content of prompt broken_base: missing_base:")
+	assert.Equal(t, int64(CODE_TEMPLATE_ERROR), errorCode(err))
+}
+
+func TestHandleGetTypedArguments(t *testing.T) {
+	maximum := 5.0
+
+	testPrompts := []promptsdb.Prompt{
+		{
+			Name: "code_review",
+			Arguments: []promptsdb.Argument{
+				{Name: "verbose", Type: promptsdb.ARGUMENT_BOOLEAN},
+				{Name: "files", Type: promptsdb.ARGUMENT_LIST, Required: true},
+				{Name: "depth", Type: promptsdb.ARGUMENT_INTEGER, Maximum: &maximum, Default: 2},
+			},
+			Content: "Review{{range .files}} {{.}}{{end}} {{.depth}} levels deep.{{if .verbose}} Explain every finding.{{end}}",
+		},
+	}
+
+	db := NewMockDB(testPrompts)
+	config := Configuration{Templating: templa.Configuration{Strictness: templa.STRICTNESS_STRICT}}
+	handler := NewPromptHandler(db, config, plog.New("/tmp/test.log"))
+
+	// The text false is false and the default is used for the missing depth
+	resp, err := handler.HandleGet(context.Background(), nil, &mcp.GetPromptParams{Name: "code_review", Arguments: map[string]string{"files": "main.go, go.mod", "verbose": "false"}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Review main.go go.mod 2 levels deep.", resp.Messages[0].Content.(*mcp.TextContent).Text)
+
+	resp, err = handler.HandleGet(context.Background(), nil, &mcp.GetPromptParams{Name: "code_review", Arguments: map[string]string{"files": `["a.go"]`, "verbose": "true", "depth": "4"}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Review a.go 4 levels deep. Explain every finding.", resp.Messages[0].Content.(*mcp.TextContent).Text)
+
+	// Values which do not convert or break a constraint fail before rendering
+	tests := []struct {
+		arguments map[string]string
+		expected  string
+	}{
+		{map[string]string{"files": "a.go", "verbose": "sure"}, `invalid arguments for prompt code_review: argument verbose: "sure" is not true or false`},
+		{map[string]string{"files": "a.go", "depth": "9"}, "invalid arguments for prompt code_review: argument depth: maximum"},
+	}
+
+	for _, test := range tests {
+		resp, err = handler.HandleGet(context.Background(), nil, &mcp.GetPromptParams{Name: "code_review", Arguments: test.arguments})
+
+		assert.Nil(t, resp)
+		assert.ErrorContains(t, err, test.expected)
+		assert.Equal(t, int64(CODE_INVALID_PARAMS), errorCode(err))
 	}
 }
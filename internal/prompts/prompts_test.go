@@ -40,15 +40,16 @@ func (m *MockDB) Read(name string) (promptsdb.Prompt, error) {
 	if p, ok := m.prompts[name]; ok {
 		return p, nil
 	}
-	return promptsdb.Prompt{}, assert.AnError
+	return promptsdb.Prompt{}, &promptsdb.PromptsDBError{Op: "read", PromptId: name, Err: promptsdb.ErrNotFound}
 }
 
-func (m *MockDB) List(query promptsdb.PromptQuery) ([]promptsdb.Prompt, error) {
+func (m *MockDB) List(query promptsdb.PromptQuery) ([]promptsdb.Prompt, int, error) {
 	results := make([]promptsdb.Prompt, 0, len(m.prompts))
 	for _, p := range m.prompts {
 		results = append(results, p)
 	}
-	return results, nil
+	page, total := query.Apply(results)
+	return page, total, nil
 }
 
 func (m *MockDB) Update(prompt promptsdb.Prompt) error {
@@ -79,7 +80,7 @@ func TestNewPromptHandler(t *testing.T) {
 	db := NewMockDB([]promptsdb.Prompt{})
 	logger := plog.New("/tmp/test.log")
 
-	handler := NewPromptHandler(db, logger)
+	handler := NewPromptHandler(db, Configuration{}, logger)
 
 	assert.NotNil(t, handler)
 	assert.Equal(t, db, handler.db)
@@ -101,7 +102,7 @@ func TestHandleList(t *testing.T) {
 	}
 	db := NewMockDB(testPrompts)
 	logger := plog.New("/tmp/test.log")
-	handler := NewPromptHandler(db, logger)
+	handler := NewPromptHandler(db, Configuration{}, logger)
 
 	req := &mcp.ListPromptsParams{}
 	resp, err := handler.HandleList(context.Background(), nil, req)
@@ -122,7 +123,7 @@ func TestHandleGet(t *testing.T) {
 	}
 	db := NewMockDB([]promptsdb.Prompt{testPrompt})
 	logger := plog.New("/tmp/test.log")
-	handler := NewPromptHandler(db, logger)
+	handler := NewPromptHandler(db, Configuration{}, logger)
 
 	req := &mcp.GetPromptParams{
 		Name: "test",
@@ -143,7 +144,7 @@ func TestHandleGet(t *testing.T) {
 func TestHandleGetError(t *testing.T) {
 	db := NewMockDB([]promptsdb.Prompt{})
 	logger := plog.New("/tmp/test.log")
-	handler := NewPromptHandler(db, logger)
+	handler := NewPromptHandler(db, Configuration{}, logger)
 
 	req := &mcp.GetPromptParams{
 		Name: "error",
@@ -157,7 +158,7 @@ func TestHandleGetError(t *testing.T) {
 func TestHandleGetEmptyName(t *testing.T) {
 	db := NewMockDB([]promptsdb.Prompt{})
 	logger := plog.New("/tmp/test.log")
-	handler := NewPromptHandler(db, logger)
+	handler := NewPromptHandler(db, Configuration{}, logger)
 
 	req := &mcp.GetPromptParams{
 		Name: "",
@@ -167,3 +168,169 @@ func TestHandleGetEmptyName(t *testing.T) {
 	assert.Error(t, err)
 	assert.Nil(t, resp)
 }
+
+func TestHandleListPagination(t *testing.T) {
+	testPrompts := []promptsdb.Prompt{
+		{Name: "test1"},
+		{Name: "test2"},
+		{Name: "test3"},
+		{Name: "test4"},
+		{Name: "test5"},
+	}
+	db := NewMockDB(testPrompts)
+	logger := plog.New("/tmp/test.log")
+	handler := NewPromptHandler(db, Configuration{PageSize: 2}, logger)
+
+	// Walk through every page following the cursors
+	names := []string{}
+	cursor := ""
+	pages := 0
+	for {
+		resp, err := handler.HandleList(context.Background(), nil, &mcp.ListPromptsParams{Cursor: cursor})
+		assert.NoError(t, err)
+		assert.LessOrEqual(t, len(resp.Prompts), 2)
+
+		for _, p := range resp.Prompts {
+			names = append(names, p.Name)
+		}
+		pages++
+
+		if resp.NextCursor == "" {
+			break
+		}
+		cursor = resp.NextCursor
+	}
+
+	assert.Equal(t, 3, pages)
+	assert.Equal(t, []string{"test1", "test2", "test3", "test4", "test5"}, names)
+}
+
+func TestHandleListInvalidCursor(t *testing.T) {
+	db := NewMockDB([]promptsdb.Prompt{{Name: "test1"}})
+	logger := plog.New("/tmp/test.log")
+	handler := NewPromptHandler(db, Configuration{}, logger)
+
+	resp, err := handler.HandleList(context.Background(), nil, &mcp.ListPromptsParams{Cursor: "not a cursor!"})
+
+	assert.Error(t, err)
+	assert.Nil(t, resp)
+}
+
+func TestHandleListArguments(t *testing.T) {
+	testPrompts := []promptsdb.Prompt{
+		{
+			Name: "review",
+			Arguments: []promptsdb.Argument{
+				{Name: "language"},
+				{Name: "code", Description: "The code to review", Required: true},
+			},
+		},
+	}
+	db := NewMockDB(testPrompts)
+	logger := plog.New("/tmp/test.log")
+	handler := NewPromptHandler(db, Configuration{}, logger)
+
+	resp, err := handler.HandleList(context.Background(), nil, &mcp.ListPromptsParams{})
+
+	assert.NoError(t, err)
+	assert.Len(t, resp.Prompts, 1)
+	assert.Equal(t, []*mcp.PromptArgument{
+		{Name: "language"},
+		{Name: "code", Description: "The code to review", Required: true},
+	}, resp.Prompts[0].Arguments)
+}
+
+func TestHandleListSource(t *testing.T) {
+	testPrompts := []promptsdb.Prompt{
+		{Name: "team", Source: "/srv/team-prompts"},
+		{Name: "unknown"},
+	}
+	db := NewMockDB(testPrompts)
+	logger := plog.New("/tmp/test.log")
+	handler := NewPromptHandler(db, Configuration{}, logger)
+
+	resp, err := handler.HandleList(context.Background(), nil, &mcp.ListPromptsParams{})
+
+	assert.NoError(t, err)
+	assert.Len(t, resp.Prompts, 2)
+	assert.Equal(t, mcp.Meta{META_SOURCE: "/srv/team-prompts"}, resp.Prompts[0].Meta)
+	assert.Nil(t, resp.Prompts[1].Meta)
+}
+
+func TestHandleGetMissingRequiredArguments(t *testing.T) {
+	testPrompt := promptsdb.Prompt{
+		Name:    "review",
+		Content: "Review this {{.language}} code: {{.code}}",
+		Arguments: []promptsdb.Argument{
+			{Name: "language", Required: true},
+			{Name: "code", Required: true},
+			{Name: "focus"},
+		},
+	}
+	db := NewMockDB([]promptsdb.Prompt{testPrompt})
+	logger := plog.New("/tmp/test.log")
+	handler := NewPromptHandler(db, Configuration{}, logger)
+
+	resp, err := handler.HandleGet(context.Background(), nil, &mcp.GetPromptParams{
+		Name:      "review",
+		Arguments: map[string]string{"language": "Go"},
+	})
+
+	assert.Error(t, err)
+	assert.Nil(t, resp)
+	assert.Contains(t, err.Error(), "code")
+	assert.NotContains(t, err.Error(), "language")
+
+	// Optional arguments may be left out
+	resp, err = handler.HandleGet(context.Background(), nil, &mcp.GetPromptParams{
+		Name:      "review",
+		Arguments: map[string]string{"language": "Go", "code": "x := 1"},
+	})
+
+	assert.NoError(t, err)
+	if textContent, ok := resp.Messages[0].Content.(*mcp.TextContent); ok {
+		assert.Equal(t, "Review this Go code: x := 1", textContent.Text)
+	} else {
+		t.Error("Expected TextContent type")
+	}
+}
+
+func TestHandleGetMessages(t *testing.T) {
+	testPrompt := promptsdb.Prompt{
+		Name: "sentiment",
+		Messages: []promptsdb.Message{
+			{Role: promptsdb.ROLE_USER, Content: "Classify: great product"},
+			{Role: promptsdb.ROLE_ASSISTANT, Content: "positive"},
+		},
+		Content: "Classify: {{.review}}",
+	}
+	db := NewMockDB([]promptsdb.Prompt{testPrompt})
+	logger := plog.New("/tmp/test.log")
+	handler := NewPromptHandler(db, Configuration{}, logger)
+
+	resp, err := handler.HandleGet(context.Background(), nil, &mcp.GetPromptParams{
+		Name:      "sentiment",
+		Arguments: map[string]string{"review": "broken on arrival"},
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, resp.Messages, 3)
+
+	expected := []struct {
+		role string
+		text string
+	}{
+		{"user", "Classify: great product"},
+		{"assistant", "positive"},
+		{"user", "Classify: broken on arrival"},
+	}
+
+	for i, message := range expected {
+		assert.Equal(t, mcp.Role(message.role), resp.Messages[i].Role)
+		if textContent, ok := resp.Messages[i].Content.(*mcp.TextContent); ok {
+			assert.Equal(t, message.text, textContent.Text)
+		} else {
+			t.Errorf("Expected TextContent type for message %d", i)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package prompts
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/hkionline/prompter/internal/plog"
+	"github.com/hkionline/prompter/internal/promptsdb"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	RESOURCE_SCHEME       = "prompt://"        // URI scheme of the prompts published as resources
+	RESOURCE_URI_TEMPLATE = "prompt://{+name}" // URI template matching every prompt, namespaced names included
+	RESOURCE_RENDERED     = "rendered"         // query parameter asking for the rendered prompt instead of the markdown
+	MIME_TYPE_MARKDOWN    = "text/markdown"    // media type of the raw prompt files
+	MIME_TYPE_TEXT        = "text/plain"       // media type of the rendered text messages
+	META_REVISION         = "revision"         // _meta key holding the revision of the prompt
+	META_ROLE             = "role"             // _meta key telling which role a rendered message is from
+)
+
+// ToResourceURI returns the URI of the prompt published as a resource
+func ToResourceURI(name string) string {
+	return RESOURCE_SCHEME + name
+}
+
+// ToMCPResource converts a stored prompt to the resource listed to clients, the
+// resource reads as the prompt file. The revision in _meta changes with the prompt.
+func ToMCPResource(prompt promptsdb.Prompt) *mcp.Resource {
+
+	resource := &mcp.Resource{
+		URI:         ToResourceURI(prompt.Name),
+		Name:        prompt.Name,
+		Title:       prompt.Title,
+		Description: prompt.Description,
+		MIMEType:    MIME_TYPE_MARKDOWN,
+	}
+
+	meta := mcp.Meta{}
+
+	if prompt.Source != "" {
+		meta[META_SOURCE] = prompt.Source
+	}
+
+	if prompt.Revision != "" {
+		meta[META_REVISION] = prompt.Revision
+	}
+
+	if len(meta) > 0 {
+		resource.Meta = meta
+	}
+
+	return resource
+}
+
+// ResourceTemplate returns the template clients can read any prompt with, e.g.
+// prompt://review/security_audit for the markdown of the prompt and
+// prompt://review/security_audit?rendered&language=Go for the prompt rendered with the arguments
+func ResourceTemplate() *mcp.ResourceTemplate {
+	return &mcp.ResourceTemplate{
+		URITemplate: RESOURCE_URI_TEMPLATE,
+		Name:        "prompt",
+		Title:       "Prompt",
+		Description: "Markdown of the named prompt, add ?rendered and the prompt arguments as query parameters for the rendered prompt",
+		MIMEType:    MIME_TYPE_MARKDOWN,
+	}
+}
+
+// HandleReadResource handles the resources/read request for prompt URIs
+func (h *PromptHandler) HandleReadResource(ctx context.Context, ss *mcp.ServerSession, req *mcp.ReadResourceParams) (*mcp.ReadResourceResult, error) {
+	h.logger.Write(plog.CLIENT, "resources/read")
+
+	name, query, err := parseResourceURI(req.URI)
+	if err != nil {
+		h.logger.Write(plog.SERVER, "Invalid prompt resource URI: %s", err.Error())
+		return nil, rpcError(CODE_INVALID_PARAMS, err)
+	}
+
+	prompt, err := h.db.Read(name)
+	if err != nil {
+		h.logger.Write(plog.SERVER, "Failed to read prompt: %s", err.Error())
+		return nil, ToRPCError(fmt.Errorf("failed to read resource %s: %w", req.URI, err))
+	}
+
+	// Partials are only included in other prompts, they are neither listed nor rendered as resources
+	if prompt.Partial {
+		h.logger.Write(plog.SERVER, "Prompt %s is a partial", name)
+		return nil, ToRPCError(fmt.Errorf("failed to read resource %s: %w: %s is a partial", req.URI, promptsdb.ErrNotFound, name))
+	}
+
+	if query.Has(RESOURCE_RENDERED) {
+		return h.readRendered(ctx, ss, req.URI, name, query)
+	}
+
+	markdown, err := prompt.Markdown()
+	if err != nil {
+		h.logger.Write(plog.SERVER, "Failed to marshal prompt: %s", err.Error())
+		return nil, ToRPCError(fmt.Errorf("failed to read resource %s: %w", req.URI, err))
+	}
+
+	return &mcp.ReadResourceResult{
+		Contents: []*mcp.ResourceContents{
+			{
+				URI:      req.URI,
+				MIMEType: MIME_TYPE_MARKDOWN,
+				Text:     string(markdown),
+			},
+		},
+	}, nil
+}
+
+// readRendered renders the prompt like prompts/get does and returns every message as
+// its own resource contents, the role of the message is kept in _meta
+func (h *PromptHandler) readRendered(ctx context.Context, ss *mcp.ServerSession, uri string, name string, query url.Values) (*mcp.ReadResourceResult, error) {
+
+	arguments := map[string]string{}
+
+	for key := range query {
+		if key != RESOURCE_RENDERED {
+			arguments[key] = query.Get(key)
+		}
+	}
+
+	result, err := h.HandleGet(ctx, ss, &mcp.GetPromptParams{Name: name, Arguments: arguments})
+	if err != nil {
+		return nil, err
+	}
+
+	contents := []*mcp.ResourceContents{}
+
+	for _, message := range result.Messages {
+
+		resource := &mcp.ResourceContents{URI: uri}
+
+		switch content := message.Content.(type) {
+		case *mcp.TextContent:
+			resource.MIMEType = MIME_TYPE_TEXT
+			resource.Text = content.Text
+		case *mcp.ImageContent:
+			resource.MIMEType = content.MIMEType
+			resource.Blob = content.Data
+		case *mcp.EmbeddedResource:
+			embedded := *content.Resource
+			resource = &embedded
+		default:
+			return nil, rpcError(CODE_INTERNAL_ERROR, fmt.Errorf("prompt %s has a message of unsupported type %T", name, content))
+		}
+
+		resource.Meta = mcp.Meta{META_ROLE: string(message.Role)}
+		contents = append(contents, resource)
+	}
+
+	return &mcp.ReadResourceResult{Contents: contents}, nil
+}
+
+// parseResourceURI returns the prompt name and the query parameters of a prompt URI
+func parseResourceURI(uri string) (string, url.Values, error) {
+
+	rest, ok := strings.CutPrefix(uri, RESOURCE_SCHEME)
+	if !ok {
+		return "", nil, fmt.Errorf("%s is not a prompt URI", uri)
+	}
+
+	name, rawQuery, _ := strings.Cut(rest, "?")
+
+	if name == "" {
+		return "", nil, fmt.Errorf("%s names no prompt", uri)
+	}
+
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid query in %s: %w", uri, err)
+	}
+
+	return name, query, nil
+}
@@ -0,0 +1,77 @@
+package prompts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hkionline/prompter/internal/plog"
+	"github.com/hkionline/prompter/internal/promptsdb"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToMCPResource(t *testing.T) {
+	resource := ToMCPResource(promptsdb.Prompt{
+		Name:        "review/code",
+		Title:       "Code review",
+		Description: "Reviews code",
+		Source:      "/srv/team-prompts",
+		Revision:    "abc123",
+	})
+
+	assert.Equal(t, "prompt://review/code", resource.URI)
+	assert.Equal(t, "review/code", resource.Name)
+	assert.Equal(t, "Code review", resource.Title)
+	assert.Equal(t, MIME_TYPE_MARKDOWN, resource.MIMEType)
+	assert.Equal(t, mcp.Meta{META_SOURCE: "/srv/team-prompts", META_REVISION: "abc123"}, resource.Meta)
+
+	assert.Nil(t, ToMCPResource(promptsdb.Prompt{Name: "plain"}).Meta)
+}
+
+func TestHandleReadResource(t *testing.T) {
+	db := NewMockDB([]promptsdb.Prompt{{
+		Name:      "sentiment",
+		Title:     "Sentiment",
+		Arguments: []promptsdb.Argument{{Name: "review", Required: true}},
+		Messages: []promptsdb.Message{
+			{Role: promptsdb.ROLE_USER, Content: "Classify: great"},
+			{Role: promptsdb.ROLE_ASSISTANT, Content: "positive"},
+		},
+		Content: "Classify: {{.review}}",
+	}})
+	handler := NewPromptHandler(db, Configuration{}, plog.New("/tmp/test.log"))
+	ctx := context.Background()
+
+	raw, err := handler.HandleReadResource(ctx, nil, &mcp.ReadResourceParams{URI: "prompt://sentiment"})
+	assert.NoError(t, err)
+	assert.Len(t, raw.Contents, 1)
+	assert.Equal(t, MIME_TYPE_MARKDOWN, raw.Contents[0].MIMEType)
+	assert.Contains(t, raw.Contents[0].Text, "name: sentiment")
+	assert.Contains(t, raw.Contents[0].Text, "role: assistant")
+	assert.Contains(t, raw.Contents[0].Text, "---\nClassify: {{.review}}")
+
+	rendered, err := handler.HandleReadResource(ctx, nil, &mcp.ReadResourceParams{URI: "prompt://sentiment?rendered&review=too+slow"})
+	assert.NoError(t, err)
+	assert.Len(t, rendered.Contents, 3)
+	assert.Equal(t, "positive", rendered.Contents[1].Text)
+	assert.Equal(t, mcp.Meta{META_ROLE: "assistant"}, rendered.Contents[1].Meta)
+	assert.Equal(t, "Classify: too slow", rendered.Contents[2].Text)
+	assert.Equal(t, MIME_TYPE_TEXT, rendered.Contents[2].MIMEType)
+	assert.Equal(t, "prompt://sentiment?rendered&review=too+slow", rendered.Contents[2].URI)
+
+	tests := []struct {
+		uri  string
+		code int64
+	}{
+		{"prompt://missing", CODE_NOT_FOUND},
+		{"prompt://", CODE_INVALID_PARAMS},
+		{"file:///etc/passwd", CODE_INVALID_PARAMS},
+		{"prompt://sentiment?rendered", CODE_INVALID_PARAMS},
+	}
+
+	for _, test := range tests {
+		_, err := handler.HandleReadResource(ctx, nil, &mcp.ReadResourceParams{URI: test.uri})
+		assert.Error(t, err, test.uri)
+		assert.Equal(t, test.code, errorCode(err), test.uri)
+	}
+}
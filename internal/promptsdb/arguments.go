@@ -0,0 +1,322 @@
+package promptsdb
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonschema"
+)
+
+const (
+	ARGUMENT_STRING  = "string"  // text argument, the type of arguments without a type
+	ARGUMENT_NUMBER  = "number"  // decimal number argument
+	ARGUMENT_INTEGER = "integer" // whole number argument
+	ARGUMENT_BOOLEAN = "boolean" // true or false argument
+	ARGUMENT_ENUM    = "enum"    // text argument accepting only the listed values
+	ARGUMENT_LIST    = "list"    // list argument, given as a JSON array or comma separated text
+	ARGUMENT_OBJECT  = "object"  // JSON object argument described by its schema
+)
+
+// argumentTypes are the types an argument can be declared with
+var argumentTypes = []string{ARGUMENT_STRING, ARGUMENT_NUMBER, ARGUMENT_INTEGER, ARGUMENT_BOOLEAN, ARGUMENT_ENUM, ARGUMENT_LIST, ARGUMENT_OBJECT}
+
+// itemTypes are the types the items of a list argument can have
+var itemTypes = []string{ARGUMENT_STRING, ARGUMENT_NUMBER, ARGUMENT_INTEGER, ARGUMENT_BOOLEAN}
+
+// Kind returns the type of the argument, arguments without a type are text
+func (a Argument) Kind() string {
+
+	if a.Type == "" {
+		return ARGUMENT_STRING
+	}
+
+	return a.Type
+}
+
+// JSONSchema describes the values the argument accepts as a JSON Schema
+func (a Argument) JSONSchema() (*jsonschema.Schema, error) {
+
+	schema := &jsonschema.Schema{}
+
+	switch a.Kind() {
+	case ARGUMENT_LIST:
+		schema.Type = "array"
+		schema.Items = scalarSchema(a.Items, a.Values)
+		schema.MinItems = a.MinItems
+		schema.MaxItems = a.MaxItems
+	case ARGUMENT_OBJECT:
+		if a.Schema != nil {
+			raw, err := json.Marshal(a.Schema)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := json.Unmarshal(raw, schema); err != nil {
+				return nil, err
+			}
+		}
+
+		if schema.Type == "" && len(schema.Types) == 0 {
+			schema.Type = "object"
+		}
+	default:
+		schema = scalarSchema(a.Kind(), a.Values)
+		schema.Minimum = a.Minimum
+		schema.Maximum = a.Maximum
+		schema.MinLength = a.MinLength
+		schema.MaxLength = a.MaxLength
+		schema.Pattern = a.Pattern
+	}
+
+	if a.Description != "" {
+		schema.Description = a.Description
+	}
+
+	if a.Default != nil {
+		raw, err := json.Marshal(a.Default)
+		if err != nil {
+			return nil, err
+		}
+
+		schema.Default = raw
+	}
+
+	return schema, nil
+}
+
+// scalarSchema describes a text, number or boolean value, values limit texts to the listed ones
+func scalarSchema(kind string, values []string) *jsonschema.Schema {
+
+	switch kind {
+	case ARGUMENT_NUMBER, ARGUMENT_INTEGER, ARGUMENT_BOOLEAN:
+		return &jsonschema.Schema{Type: kind}
+	}
+
+	schema := &jsonschema.Schema{Type: "string"}
+
+	for _, value := range values {
+		schema.Enum = append(schema.Enum, value)
+	}
+
+	return schema
+}
+
+// Resolve turns the text value the argument is given with into a value of the argument type
+// and checks it against the argument schema, arguments given no value get their fallback
+func (a Argument) Resolve(value string) (any, error) {
+
+	if value == "" {
+		return a.Fallback(), nil
+	}
+
+	typed, err := a.coerce(value)
+	if err != nil {
+		return nil, err
+	}
+
+	schema, err := a.JSONSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	resolved, err := schema.Resolve(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := resolved.Validate(typed); err != nil {
+		return nil, invalidValue(err)
+	}
+
+	return typed, nil
+}
+
+// Fallback returns the value of an argument which is not given, the default when the argument
+// has one and otherwise an empty value: false, an empty list or object, or empty text
+func (a Argument) Fallback() any {
+
+	if a.Default != nil {
+		return a.Default
+	}
+
+	switch a.Kind() {
+	case ARGUMENT_BOOLEAN:
+		return false
+	case ARGUMENT_LIST:
+		return []any{}
+	case ARGUMENT_OBJECT:
+		return map[string]any{}
+	default:
+		return ""
+	}
+}
+
+// coerce converts the text value to the argument type
+func (a Argument) coerce(value string) (any, error) {
+
+	switch a.Kind() {
+	case ARGUMENT_LIST:
+		// Text which is not a JSON array, such as "[draft] notes, other", is split at the commas
+		var items []any
+		if err := json.Unmarshal([]byte(value), &items); err != nil || items == nil {
+			items = []any{}
+			for _, part := range strings.Split(value, ",") {
+				items = append(items, strings.TrimSpace(part))
+			}
+		}
+
+		// Text items are converted to the item type, also within JSON arrays
+		for i, item := range items {
+			if text, ok := item.(string); ok {
+				converted, err := coerceScalar(a.Items, text)
+				if err != nil {
+					return nil, err
+				}
+				items[i] = converted
+			}
+		}
+
+		return items, nil
+	case ARGUMENT_OBJECT:
+		var object any
+		if err := json.Unmarshal([]byte(value), &object); err != nil {
+			return nil, fmt.Errorf("%q is not valid JSON", value)
+		}
+		return object, nil
+	default:
+		return coerceScalar(a.Kind(), value)
+	}
+}
+
+// coerceScalar converts the text to a number, a whole number or a boolean, other kinds stay text
+func coerceScalar(kind string, value string) (any, error) {
+
+	switch kind {
+	case ARGUMENT_NUMBER:
+		n, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+			return nil, fmt.Errorf("%q is not a number", value)
+		}
+		return n, nil
+	case ARGUMENT_INTEGER:
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a whole number", value)
+		}
+		return n, nil
+	case ARGUMENT_BOOLEAN:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("%q is not true or false", value)
+		}
+		return b, nil
+	default:
+		return value, nil
+	}
+}
+
+// ResolveArguments turns the text values the prompt is invoked with into values of the declared
+// argument types, declared arguments which are not given get their fallback and undeclared
+// arguments are kept as text
+func (p Prompt) ResolveArguments(values map[string]string) (map[string]any, error) {
+
+	resolved := map[string]any{}
+
+	for name, value := range values {
+		resolved[name] = value
+	}
+
+	for _, argument := range p.Arguments {
+		value, err := argument.Resolve(values[argument.Name])
+		if err != nil {
+			return nil, fmt.Errorf("argument %s: %w", argument.Name, err)
+		}
+
+		resolved[argument.Name] = value
+	}
+
+	return resolved, nil
+}
+
+// ArgumentsSchema describes the arguments of the prompt as a JSON Schema object,
+// the arguments being its properties
+func (p Prompt) ArgumentsSchema() (*jsonschema.Schema, error) {
+
+	schema := &jsonschema.Schema{
+		Type:       "object",
+		Properties: map[string]*jsonschema.Schema{},
+		Required:   p.RequiredArguments(),
+	}
+
+	for _, argument := range p.Arguments {
+		property, err := argument.JSONSchema()
+		if err != nil {
+			return nil, fmt.Errorf("argument %s: %w", argument.Name, err)
+		}
+
+		schema.Properties[argument.Name] = property
+	}
+
+	return schema, nil
+}
+
+// validateArguments checks that the arguments have known types, a valid schema and defaults
+// the schema accepts
+func (p Prompt) validateArguments() error {
+
+	for _, argument := range p.Arguments {
+		if err := argument.validate(); err != nil {
+			return fmt.Errorf("%w: argument %s %w", ErrInvalidPrompt, argument.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// validate checks a single argument declaration
+func (a Argument) validate() error {
+
+	if !slices.Contains(argumentTypes, a.Kind()) {
+		return fmt.Errorf("has unknown type %q", a.Type)
+	}
+
+	if a.Kind() == ARGUMENT_ENUM && len(a.Values) == 0 {
+		return fmt.Errorf("is an enum without values")
+	}
+
+	if a.Items != "" && (a.Kind() != ARGUMENT_LIST || !slices.Contains(itemTypes, a.Items)) {
+		return fmt.Errorf("has invalid item type %q", a.Items)
+	}
+
+	if a.Schema != nil && a.Kind() != ARGUMENT_OBJECT {
+		return fmt.Errorf("has a schema but is not an object")
+	}
+
+	schema, err := a.JSONSchema()
+	if err != nil {
+		return fmt.Errorf("has an invalid schema: %w", err)
+	}
+
+	resolved, err := schema.Resolve(nil)
+	if err != nil {
+		return fmt.Errorf("has an invalid schema: %w", err)
+	}
+
+	if a.Default != nil {
+		if err := resolved.Validate(a.Default); err != nil {
+			return fmt.Errorf("has an invalid default: %w", invalidValue(err))
+		}
+	}
+
+	return nil
+}
+
+// invalidValue drops the schema location from the errors of validating a single value
+func invalidValue(err error) error {
+	return errors.New(strings.TrimPrefix(err.Error(), "validating root: "))
+}
@@ -0,0 +1,187 @@
+package promptsdb
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// typedPrompt declares an argument of every type
+func typedPrompt() Prompt {
+
+	minimum, maximum := 1.0, 10.0
+	maxLength, minItems := 5, 1
+
+	return Prompt{
+		Name: "typed",
+		Arguments: []Argument{
+			{Name: "language", MaxLength: &maxLength},
+			{Name: "verbose", Type: ARGUMENT_BOOLEAN},
+			{Name: "limit", Type: ARGUMENT_INTEGER, Minimum: &minimum, Maximum: &maximum, Default: 3},
+			{Name: "ratio", Type: ARGUMENT_NUMBER},
+			{Name: "depth", Type: ARGUMENT_ENUM, Values: []string{"quick", "thorough"}},
+			{Name: "files", Type: ARGUMENT_LIST, MinItems: &minItems},
+			{Name: "ids", Type: ARGUMENT_LIST, Items: ARGUMENT_INTEGER},
+			{Name: "options", Type: ARGUMENT_OBJECT, Schema: map[string]any{"required": []any{"format"}}},
+		},
+	}
+}
+
+func TestResolveArguments(t *testing.T) {
+	prompt := typedPrompt()
+
+	// Arguments which are not given get their default or an empty value
+	resolved, err := prompt.ResolveArguments(map[string]string{"extra": "kept"})
+	if err != nil {
+		t.Fatalf("ResolveArguments() returned error: %v", err)
+	}
+
+	expected := map[string]any{
+		"language": "",
+		"verbose":  false,
+		"limit":    3,
+		"ratio":    "",
+		"depth":    "",
+		"files":    []any{},
+		"ids":      []any{},
+		"options":  map[string]any{},
+		"extra":    "kept",
+	}
+
+	if !reflect.DeepEqual(resolved, expected) {
+		t.Errorf("ResolveArguments() returned %#v, expected %#v", resolved, expected)
+	}
+
+	// Given values are converted to the argument types
+	resolved, err = prompt.ResolveArguments(map[string]string{
+		"language": "Go",
+		"verbose":  "true",
+		"limit":    "7",
+		"ratio":    "0.5",
+		"depth":    "quick",
+		"files":    "main.go, go.mod",
+		"ids":      "1,2",
+		"options":  `{"format": "markdown"}`,
+	})
+	if err != nil {
+		t.Fatalf("ResolveArguments() returned error: %v", err)
+	}
+
+	expected = map[string]any{
+		"language": "Go",
+		"verbose":  true,
+		"limit":    7,
+		"ratio":    0.5,
+		"depth":    "quick",
+		"files":    []any{"main.go", "go.mod"},
+		"ids":      []any{1, 2},
+		"options":  map[string]any{"format": "markdown"},
+	}
+
+	if !reflect.DeepEqual(resolved, expected) {
+		t.Errorf("ResolveArguments() returned %#v, expected %#v", resolved, expected)
+	}
+
+	// Lists are also given as JSON arrays
+	resolved, err = prompt.ResolveArguments(map[string]string{"files": `["a, b.go", "c.go"]`})
+	if err != nil || !reflect.DeepEqual(resolved["files"], []any{"a, b.go", "c.go"}) {
+		t.Errorf("Expected the JSON array as the list, got %#v and %v", resolved["files"], err)
+	}
+
+	// Text items of JSON arrays are converted to the item type
+	resolved, err = prompt.ResolveArguments(map[string]string{"ids": `["1", 2]`})
+	if err != nil || !reflect.DeepEqual(resolved["ids"], []any{1, 2.0}) {
+		t.Errorf("Expected the JSON array items as whole numbers, got %#v and %v", resolved["ids"], err)
+	}
+
+	// Text starting with a bracket which is not a JSON array is split at the commas
+	resolved, err = prompt.ResolveArguments(map[string]string{"files": "[draft] notes, other"})
+	if err != nil || !reflect.DeepEqual(resolved["files"], []any{"[draft] notes", "other"}) {
+		t.Errorf("Expected the text split at the commas, got %#v and %v", resolved["files"], err)
+	}
+}
+
+func TestResolveArgumentsErrors(t *testing.T) {
+	prompt := typedPrompt()
+
+	tests := []struct {
+		arguments map[string]string
+		expected  string
+	}{
+		{map[string]string{"verbose": "yes"}, `argument verbose: "yes" is not true or false`},
+		{map[string]string{"limit": "many"}, `argument limit: "many" is not a whole number`},
+		{map[string]string{"limit": "12"}, "argument limit: maximum"},
+		{map[string]string{"ratio": "half"}, `argument ratio: "half" is not a number`},
+		{map[string]string{"ratio": "NaN"}, `argument ratio: "NaN" is not a number`},
+		{map[string]string{"ratio": "+Inf"}, `argument ratio: "+Inf" is not a number`},
+		{map[string]string{"depth": "deep"}, "argument depth: enum"},
+		{map[string]string{"language": "TypeScript"}, "argument language: maxLength"},
+		{map[string]string{"files": "[]"}, "argument files: minItems"},
+		{map[string]string{"ids": "1,x"}, `argument ids: "x" is not a whole number`},
+		{map[string]string{"options": "{}"}, "argument options: required"},
+		{map[string]string{"options": "markdown"}, `argument options: "markdown" is not valid JSON`},
+	}
+
+	for _, test := range tests {
+		_, err := prompt.ResolveArguments(test.arguments)
+		if err == nil || !strings.HasPrefix(err.Error(), test.expected) {
+			t.Errorf("%v: expected error starting with %q, got %v", test.arguments, test.expected, err)
+		}
+	}
+}
+
+func TestArgumentsSchema(t *testing.T) {
+	prompt := Prompt{
+		Name: "typed",
+		Arguments: []Argument{
+			{Name: "language", Required: true, Description: "Language of the code"},
+			{Name: "depth", Type: ARGUMENT_ENUM, Values: []string{"quick", "thorough"}, Default: "quick"},
+			{Name: "files", Type: ARGUMENT_LIST, Items: ARGUMENT_NUMBER},
+		},
+	}
+
+	schema, err := prompt.ArgumentsSchema()
+	if err != nil {
+		t.Fatalf("ArgumentsSchema() returned error: %v", err)
+	}
+
+	data, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatalf("Failed to marshal schema: %v", err)
+	}
+
+	expected := `{"type":"object","required":["language"],"properties":{` +
+		`"depth":{"type":"string","default":"quick","enum":["quick","thorough"]},` +
+		`"files":{"type":"array","items":{"type":"number"}},` +
+		`"language":{"type":"string","description":"Language of the code"}}}`
+
+	if string(data) != expected {
+		t.Errorf("ArgumentsSchema() returned %s, expected %s", data, expected)
+	}
+}
+
+func TestValidateArguments(t *testing.T) {
+	tests := []struct {
+		argument Argument
+		valid    bool
+	}{
+		{Argument{Name: "plain"}, true},
+		{Argument{Name: "flag", Type: ARGUMENT_BOOLEAN, Default: true}, true},
+		{Argument{Name: "flag", Type: ARGUMENT_BOOLEAN, Default: "yes"}, false},
+		{Argument{Name: "mode", Type: "float"}, false},
+		{Argument{Name: "mode", Type: ARGUMENT_ENUM}, false},
+		{Argument{Name: "ids", Type: ARGUMENT_LIST, Items: ARGUMENT_OBJECT}, false},
+		{Argument{Name: "ids", Items: ARGUMENT_INTEGER}, false},
+		{Argument{Name: "name", Schema: map[string]any{"type": "string"}}, false},
+		{Argument{Name: "name", Pattern: "("}, false},
+		{Argument{Name: "options", Type: ARGUMENT_OBJECT, Schema: map[string]any{"type": 1}}, false},
+	}
+
+	for _, test := range tests {
+		err := Prompt{Name: "typed", Arguments: []Argument{test.argument}}.Validate()
+		if (err == nil) != test.valid {
+			t.Errorf("%+v: expected valid %v, got %v", test.argument, test.valid, err)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package promptsdb
+
+import (
+	"errors"
+)
+
+// Sentinel errors the storage providers wrap, match them with errors.Is
+var (
+	ErrNotFound      = errors.New("prompt not found")
+	ErrAlreadyExists = errors.New("prompt already exists")
+	ErrInvalidPrompt = errors.New("invalid prompt")
+	ErrReadOnly      = errors.New("prompt is read-only")
+	ErrConflict      = errors.New("prompt was changed since it was read")
+)
+
+// PromptsDBError tells which operation failed for which prompt, the cause
+// is either one of the sentinel errors or an error of the storage itself
+type PromptsDBError struct {
+	Op       string // operation that failed, e.g. create or delete
+	PromptId string // prompt the operation was made for, empty when unknown
+	Err      error  // cause of the failure
+}
+
+func (p *PromptsDBError) Error() string {
+
+	message := "could not " + p.Op + " prompt"
+
+	if p.PromptId != "" {
+		message += " " + p.PromptId
+	}
+
+	if p.Err != nil {
+		message += ": " + p.Err.Error()
+	}
+
+	return message
+}
+
+func (p *PromptsDBError) Unwrap() error {
+	return p.Err
+}
+
+// newError wraps the cause of a failed operation
+func newError(op string, promptId string, err error) error {
+	return &PromptsDBError{Op: op, PromptId: promptId, Err: err}
+}
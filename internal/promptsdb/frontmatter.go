@@ -0,0 +1,45 @@
+package promptsdb
+
+import (
+	"bytes"
+	"errors"
+)
+
+const (
+	FRONTMATTER_DELIMITER = "---" // line opening and closing the frontmatter of a prompt file
+)
+
+// splitFrontmatter separates the YAML frontmatter of a prompt file from the content
+// following it. The file must start with a delimiter line and the frontmatter ends on the
+// next delimiter line, so delimiters appearing later in the content are left alone.
+// A UTF-8 byte order mark is dropped and CRLF line endings are read as LF.
+func splitFrontmatter(file []byte) ([]byte, []byte, error) {
+
+	file = bytes.TrimPrefix(file, []byte("\xef\xbb\xbf"))
+	file = bytes.ReplaceAll(file, []byte("\r\n"), []byte("\n"))
+
+	line, rest, _ := bytes.Cut(file, []byte("\n"))
+
+	if !isDelimiter(line) {
+		return nil, nil, errors.New("failed to extract prompt contents: the file does not start with the frontmatter delimiter " + FRONTMATTER_DELIMITER)
+	}
+
+	frontmatter := rest
+
+	for len(rest) > 0 {
+
+		start := len(frontmatter) - len(rest)
+		line, rest, _ = bytes.Cut(rest, []byte("\n"))
+
+		if isDelimiter(line) {
+			return frontmatter[:start], rest, nil
+		}
+	}
+
+	return nil, nil, errors.New("failed to extract prompt contents: the frontmatter is not closed with " + FRONTMATTER_DELIMITER)
+}
+
+// isDelimiter reports whether the line delimits the frontmatter, trailing white space is allowed
+func isDelimiter(line []byte) bool {
+	return string(bytes.TrimRight(line, " \t")) == FRONTMATTER_DELIMITER
+}
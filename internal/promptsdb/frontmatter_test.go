@@ -0,0 +1,323 @@
+package promptsdb
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hkionline/prompter/internal/plog"
+)
+
+func TestSplitFrontmatter(t *testing.T) {
+	tests := []struct {
+		name        string
+		file        string
+		frontmatter string
+		content     string
+		fails       bool
+	}{
+		{"plain", "---\nname: plain\n---\nContent", "name: plain\n", "Content", false},
+		{"horizontal rules in content", "---\nname: rules\n---\nAbove\n\n---\n\nBelow\n---\n", "name: rules\n", "Above\n\n---\n\nBelow\n---\n", false},
+		{"CRLF", "---\r\nname: crlf\r\n---\r\nFirst\r\nSecond", "name: crlf\n", "First\nSecond", false},
+		{"BOM", "\xef\xbb\xbf---\nname: bom\n---\nContent", "name: bom\n", "Content", false},
+		{"trailing white space on delimiters", "--- \nname: spaced\n---\t\nContent", "name: spaced\n", "Content", false},
+		{"empty frontmatter", "---\n---\nContent", "", "Content", false},
+		{"no content", "---\nname: empty\n---", "name: empty\n", "", false},
+		{"dashes inside a value", "---\nname: dashes\ndescription: a --- b\n---\nContent", "name: dashes\ndescription: a --- b\n", "Content", false},
+		{"no opening delimiter", "name: missing\n---\nContent", "", "", true},
+		{"unclosed", "---\nname: unclosed\nContent", "", "", true},
+		{"longer delimiter", "----\nname: long\n----\nContent", "", "", true},
+	}
+
+	for _, test := range tests {
+		frontmatter, content, err := splitFrontmatter([]byte(test.file))
+
+		if test.fails {
+			if err == nil {
+				t.Errorf("%s: expected error", test.name)
+			} else if !strings.Contains(err.Error(), "failed to extract prompt contents") {
+				t.Errorf("%s: expected error about extracting contents, got %v", test.name, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+
+		if string(frontmatter) != test.frontmatter {
+			t.Errorf("%s: expected frontmatter %q, got %q", test.name, test.frontmatter, frontmatter)
+		}
+		if string(content) != test.content {
+			t.Errorf("%s: expected content %q, got %q", test.name, test.content, content)
+		}
+	}
+}
+
+func TestLoadPromptExtraRoundTrip(t *testing.T) {
+	tempDir := t.TempDir()
+
+	promptContent := "\xef\xbb\xbf---\r\n" +
+		"name: extended\r\n" +
+		"title: Extended\r\n" +
+		"owner: platform-team\r\n" +
+		"review:\r\n" +
+		"  cadence: monthly\r\n" +
+		"  reviewers: [alice, bob]\r\n" +
+		"---\r\n" +
+		"Intro\r\n" +
+		"\r\n" +
+		"---\r\n" +
+		"\r\n" +
+		"After the rule"
+
+	if err := os.WriteFile(filepath.Join(tempDir, "extended.md"), []byte(promptContent), 0644); err != nil {
+		t.Fatalf("Failed to create prompt file: %v", err)
+	}
+
+	prompt, err := loadPrompt(filepath.Join(tempDir, "extended.md"), plog.New(filepath.Join(t.TempDir(), "test.log")))
+	if err != nil {
+		t.Fatalf("Failed to load prompt: %v", err)
+	}
+
+	if prompt.Name != "extended" || prompt.Title != "Extended" {
+		t.Errorf("Expected modeled fields to be read, got %+v", prompt)
+	}
+	if prompt.Content != "Intro\n\n---\n\nAfter the rule" {
+		t.Errorf("Expected content with the horizontal rule, got %q", prompt.Content)
+	}
+
+	expectedExtra := map[string]any{
+		"owner": "platform-team",
+		"review": map[string]any{
+			"cadence":   "monthly",
+			"reviewers": []any{"alice", "bob"},
+		},
+	}
+	if !reflect.DeepEqual(prompt.Extra, expectedExtra) {
+		t.Errorf("Expected extra metadata %v, got %v", expectedExtra, prompt.Extra)
+	}
+
+	// Saving keeps the metadata the prompt does not model
+	provider, err := NewPromptsFsProvider(tempDir, "")
+	if err != nil {
+		t.Fatalf("Failed to create FsProvider: %v", err)
+	}
+
+	prompt.Title = "Changed"
+	if err = provider.Update(prompt); err != nil {
+		t.Fatalf("Failed to update prompt: %v", err)
+	}
+
+	reloaded, err := loadPrompt(filepath.Join(tempDir, "extended.md"), plog.New(filepath.Join(t.TempDir(), "test.log")))
+	if err != nil {
+		t.Fatalf("Failed to load saved prompt: %v", err)
+	}
+
+	if reloaded.Title != "Changed" || !reflect.DeepEqual(reloaded.Extra, expectedExtra) {
+		t.Errorf("Expected changed title and kept metadata, got %+v", reloaded)
+	}
+	if reloaded.Content != prompt.Content {
+		t.Errorf("Expected content %q to survive saving, got %q", prompt.Content, reloaded.Content)
+	}
+}
+
+func TestLoadPromptMessages(t *testing.T) {
+	tempDir := t.TempDir()
+
+	promptContent := `---
+name: few_shot
+messages:
+  - role: user
+    content: Classify "great product"
+  - role: assistant
+    content: positive
+  - content: Classify "broken on arrival"
+---
+Classify "{{.review}}"`
+
+	if err := os.WriteFile(filepath.Join(tempDir, "few_shot.md"), []byte(promptContent), 0644); err != nil {
+		t.Fatalf("Failed to create prompt file: %v", err)
+	}
+
+	prompt, err := loadPrompt(filepath.Join(tempDir, "few_shot.md"), plog.New(filepath.Join(t.TempDir(), "test.log")))
+	if err != nil {
+		t.Fatalf("Failed to load prompt: %v", err)
+	}
+
+	expected := []Message{
+		{Role: ROLE_USER, Content: `Classify "great product"`},
+		{Role: ROLE_ASSISTANT, Content: "positive"},
+		{Role: ROLE_USER, Content: `Classify "broken on arrival"`},
+		{Role: ROLE_USER, Content: `Classify "{{.review}}"`},
+	}
+	if conversation := prompt.Conversation(); !reflect.DeepEqual(conversation, expected) {
+		t.Errorf("Expected conversation %v, got %v", expected, conversation)
+	}
+
+	// Unknown roles are rejected when loading
+	invalidContent := "---\nname: invalid\nmessages:\n  - role: system\n    content: Be terse\n---\nHi"
+
+	if err = os.WriteFile(filepath.Join(tempDir, "invalid.md"), []byte(invalidContent), 0644); err != nil {
+		t.Fatalf("Failed to create prompt file: %v", err)
+	}
+
+	if _, err = loadPrompt(filepath.Join(tempDir, "invalid.md"), plog.New(filepath.Join(t.TempDir(), "test.log"))); !errors.Is(err, ErrInvalidPrompt) {
+		t.Errorf("Expected ErrInvalidPrompt for an unknown role, got %v", err)
+	}
+}
+
+func TestLoadPromptPartial(t *testing.T) {
+	tempDir := t.TempDir()
+
+	promptContent := "---\nname: style_guide\npartial: true\n---\nWrite in plain English."
+
+	if err := os.WriteFile(filepath.Join(tempDir, "style_guide.md"), []byte(promptContent), 0644); err != nil {
+		t.Fatalf("Failed to create prompt file: %v", err)
+	}
+
+	prompt, err := loadPrompt(filepath.Join(tempDir, "style_guide.md"), plog.New(filepath.Join(t.TempDir(), "test.log")))
+	if err != nil {
+		t.Fatalf("Failed to load prompt: %v", err)
+	}
+
+	if !prompt.Partial {
+		t.Error("Expected the prompt to be a partial")
+	}
+
+	if len(prompt.Extra) != 0 {
+		t.Errorf("Expected partial not to end up in the extra frontmatter, got %v", prompt.Extra)
+	}
+
+	// The flag is written back only for partials
+	markdown, err := prompt.Markdown()
+	if err != nil {
+		t.Fatalf("Failed to marshal prompt: %v", err)
+	}
+
+	if !strings.Contains(string(markdown), "partial: true") {
+		t.Errorf("Expected the partial flag in the frontmatter, got %s", markdown)
+	}
+
+	prompt.Partial = false
+	if markdown, _ = prompt.Markdown(); strings.Contains(string(markdown), "partial") {
+		t.Errorf("Expected no partial flag in the frontmatter, got %s", markdown)
+	}
+}
+
+func TestLoadPromptExtends(t *testing.T) {
+	tempDir := t.TempDir()
+
+	promptContent := "---\nname: security_review\nextends: base_review\nmessages:\n  - content: Context first\n---\n{{define \"focus\"}}Look for injections.{{end}}"
+
+	if err := os.WriteFile(filepath.Join(tempDir, "security_review.md"), []byte(promptContent), 0644); err != nil {
+		t.Fatalf("Failed to create prompt file: %v", err)
+	}
+
+	prompt, err := loadPrompt(filepath.Join(tempDir, "security_review.md"), plog.New(filepath.Join(t.TempDir(), "test.log")))
+	if err != nil {
+		t.Fatalf("Failed to load prompt: %v", err)
+	}
+
+	if prompt.Extends != "base_review" || len(prompt.Extra) != 0 {
+		t.Errorf("Expected the prompt to extend base_review without extra frontmatter, got %q and %v", prompt.Extends, prompt.Extra)
+	}
+
+	// The contents extending the base are sent even when they are empty
+	prompt.Content = ""
+	if conversation := prompt.Conversation(); len(conversation) != 2 {
+		t.Errorf("Expected the contents after the messages, got %v", conversation)
+	}
+}
+
+func TestPromptConversation(t *testing.T) {
+	tests := []struct {
+		name     string
+		prompt   Prompt
+		expected []Message
+	}{
+		{"contents only", Prompt{Content: "Hi"}, []Message{{Role: ROLE_USER, Content: "Hi"}}},
+		{"empty prompt", Prompt{}, []Message{{Role: ROLE_USER}}},
+		{
+			"messages without contents",
+			Prompt{Messages: []Message{{Role: ROLE_USER, Content: "Hi"}, {Role: ROLE_ASSISTANT, Content: "Hello"}}},
+			[]Message{{Role: ROLE_USER, Content: "Hi"}, {Role: ROLE_ASSISTANT, Content: "Hello"}},
+		},
+	}
+
+	for _, test := range tests {
+		if conversation := test.prompt.Conversation(); !reflect.DeepEqual(conversation, test.expected) {
+			t.Errorf("%s: expected conversation %v, got %v", test.name, test.expected, conversation)
+		}
+	}
+}
+
+func TestLoadPromptTypedArguments(t *testing.T) {
+	tempDir := t.TempDir()
+
+	promptContent := `---
+name: code_review
+arguments:
+  - language
+  - name: verbose
+    type: boolean
+    default: false
+  - name: files
+    type: list
+    min_items: 1
+  - name: depth
+    type: integer
+    minimum: 1
+    maximum: 5
+    default: 2
+  - name: options
+    type: object
+    schema:
+      properties:
+        format:
+          type: string
+---
+Review {{range .files}}{{.}} {{end}}`
+
+	if err := os.WriteFile(filepath.Join(tempDir, "code_review.md"), []byte(promptContent), 0644); err != nil {
+		t.Fatalf("Failed to create prompt file: %v", err)
+	}
+
+	prompt, err := loadPrompt(filepath.Join(tempDir, "code_review.md"), plog.New(filepath.Join(t.TempDir(), "test.log")))
+	if err != nil {
+		t.Fatalf("Failed to load prompt: %v", err)
+	}
+
+	if len(prompt.Arguments) != 5 || prompt.Arguments[1].Type != ARGUMENT_BOOLEAN || prompt.Arguments[3].Default != 2 || *prompt.Arguments[3].Maximum != 5 {
+		t.Errorf("Expected the typed arguments, got %+v", prompt.Arguments)
+	}
+
+	// Arguments without details are still written as plain names
+	markdown, err := prompt.Markdown()
+	if err != nil {
+		t.Fatalf("Failed to marshal prompt: %v", err)
+	}
+
+	for _, expected := range []string{"- language\n", "type: boolean", "min_items: 1", "maximum: 5", "format:"} {
+		if !strings.Contains(string(markdown), expected) {
+			t.Errorf("Expected %q in the frontmatter, got %s", expected, markdown)
+		}
+	}
+
+	// Arguments with an unknown type or a default outside their schema are rejected
+	for _, arguments := range []string{"[{name: depth, type: float}]", "[{name: depth, type: integer, maximum: 5, default: 7}]", "[{name: mode, type: enum}]"} {
+		content := "---\nname: broken\narguments: " + arguments + "\n---\nText"
+
+		if err := os.WriteFile(filepath.Join(tempDir, "broken.md"), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create prompt file: %v", err)
+		}
+
+		if _, err := loadPrompt(filepath.Join(tempDir, "broken.md"), plog.New(filepath.Join(t.TempDir(), "test.log"))); !errors.Is(err, ErrInvalidPrompt) {
+			t.Errorf("%s: expected ErrInvalidPrompt, got %v", arguments, err)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package promptsdb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	LOCK_STALE   = 10 * time.Second           // age after which a lock left behind by a crashed writer is broken
+	LOCK_TIMEOUT = LOCK_STALE + 5*time.Second // how long a write waits for another writer, long enough to outlast a stale lock
+	LOCK_RETRY   = 10 * time.Millisecond      // interval of checking whether a taken lock has been released
+)
+
+// lockFile takes the lock of the prompt file so that prompter instances sharing the
+// prompts directory do not interleave their writes. The lock is a hidden file next to
+// the prompt file created exclusively, which works on every platform and filesystem
+// unlike advisory locks. The returned function releases the lock.
+func lockFile(path string) (func(), error) {
+
+	lock := filepath.Join(filepath.Dir(path), "."+filepath.Base(path)+".lock")
+	deadline := time.Now().Add(LOCK_TIMEOUT)
+
+	// The folder of a new namespace is created along with its first prompt
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	for {
+		file, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+
+		if err == nil {
+			fmt.Fprintf(file, "%d\n", os.Getpid())
+			file.Close()
+
+			return func() { os.Remove(lock) }, nil
+		}
+
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+
+		// A writer that crashed never releases its lock
+		if info, err := os.Stat(lock); err == nil && time.Since(info.ModTime()) > LOCK_STALE {
+			breakLock(lock)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("prompt file %s is locked by another writer, remove %s if no other prompter is running", path, lock)
+		}
+
+		time.Sleep(LOCK_RETRY)
+	}
+}
+
+// breakLock removes a stale lock. Removing it by name could remove the lock another writer
+// took after breaking the same stale lock, so the lock is first renamed to a name of its own,
+// which only one writer succeeds in. A lock which turns out to be fresh once renamed was
+// taken again meanwhile and is put back, unless yet another writer has taken the lock.
+func breakLock(lock string) {
+
+	stale := fmt.Sprintf("%s.%d.%d.stale", lock, os.Getpid(), time.Now().UnixNano())
+
+	if err := os.Rename(lock, stale); err != nil {
+		return
+	}
+
+	if info, err := os.Stat(stale); err == nil && time.Since(info.ModTime()) <= LOCK_STALE {
+		os.Link(stale, lock)
+	}
+
+	os.Remove(stale)
+}
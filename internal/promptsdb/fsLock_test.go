@@ -0,0 +1,288 @@
+package promptsdb
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hkionline/prompter/internal/plog"
+)
+
+func TestLockFileWaitsForRelease(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locked.md")
+
+	unlock, err := lockFile(path)
+	if err != nil {
+		t.Fatalf("Failed to take lock: %v", err)
+	}
+
+	released := make(chan time.Time, 1)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		released <- time.Now()
+		unlock()
+	}()
+
+	unlockSecond, err := lockFile(path)
+	if err != nil {
+		t.Fatalf("Failed to take released lock: %v", err)
+	}
+	defer unlockSecond()
+
+	select {
+	case <-released:
+	default:
+		t.Error("Expected the second writer to wait for the lock to be released")
+	}
+}
+
+func TestLockFileBreaksStaleLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stale.md")
+	lock := filepath.Join(filepath.Dir(path), ".stale.md.lock")
+
+	if err := os.WriteFile(lock, []byte("12345\n"), 0644); err != nil {
+		t.Fatalf("Failed to create lock file: %v", err)
+	}
+
+	old := time.Now().Add(-2 * LOCK_STALE)
+	if err := os.Chtimes(lock, old, old); err != nil {
+		t.Fatalf("Failed to age lock file: %v", err)
+	}
+
+	start := time.Now()
+
+	unlock, err := lockFile(path)
+	if err != nil {
+		t.Fatalf("Failed to take stale lock: %v", err)
+	}
+
+	if time.Since(start) > time.Second {
+		t.Error("Expected a stale lock to be broken without waiting")
+	}
+
+	unlock()
+
+	if _, err = os.Stat(lock); !os.IsNotExist(err) {
+		t.Error("Expected lock file to be removed on release")
+	}
+}
+
+func TestLockFileWaitsUntilLockIsStale(t *testing.T) {
+	// A writer waiting for a lock left behind by a crashed writer breaks it instead of giving up
+	if LOCK_TIMEOUT <= LOCK_STALE {
+		t.Errorf("Expected LOCK_TIMEOUT %v to be longer than LOCK_STALE %v", LOCK_TIMEOUT, LOCK_STALE)
+	}
+
+	path := filepath.Join(t.TempDir(), "aging.md")
+	lock := filepath.Join(filepath.Dir(path), ".aging.md.lock")
+
+	if err := os.WriteFile(lock, []byte("12345\n"), 0644); err != nil {
+		t.Fatalf("Failed to create lock file: %v", err)
+	}
+
+	// The lock turns stale while waiting for it
+	aged := time.Now().Add(-LOCK_STALE + 200*time.Millisecond)
+	if err := os.Chtimes(lock, aged, aged); err != nil {
+		t.Fatalf("Failed to age lock file: %v", err)
+	}
+
+	unlock, err := lockFile(path)
+	if err != nil {
+		t.Fatalf("Expected the lock to be broken once stale, got: %v", err)
+	}
+
+	unlock()
+}
+
+func TestLockFileBreaksStaleLockOnce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "contested.md")
+	lock := filepath.Join(filepath.Dir(path), ".contested.md.lock")
+
+	if err := os.WriteFile(lock, []byte("12345\n"), 0644); err != nil {
+		t.Fatalf("Failed to create lock file: %v", err)
+	}
+
+	old := time.Now().Add(-2 * LOCK_STALE)
+	if err := os.Chtimes(lock, old, old); err != nil {
+		t.Fatalf("Failed to age lock file: %v", err)
+	}
+
+	var mu sync.Mutex
+	holders, most := 0, 0
+
+	var wg sync.WaitGroup
+
+	// Writers seeing the same stale lock do not break the lock one of them took after it
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			unlock, err := lockFile(path)
+			if err != nil {
+				t.Errorf("Failed to take lock: %v", err)
+				return
+			}
+
+			mu.Lock()
+			holders++
+			most = max(most, holders)
+			mu.Unlock()
+
+			time.Sleep(20 * time.Millisecond)
+
+			mu.Lock()
+			holders--
+			mu.Unlock()
+
+			unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	if most != 1 {
+		t.Errorf("Expected a single writer to hold the lock at a time, %d did", most)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 0 {
+		t.Errorf("Expected no lock files to be left behind, found %v", entries)
+	}
+}
+
+func TestFsProviderReadsWhileWaitingForLock(t *testing.T) {
+	tempDir := t.TempDir()
+
+	provider, err := NewPromptsFsProvider(tempDir, "")
+	if err != nil {
+		t.Fatalf("Failed to create FsProvider: %v", err)
+	}
+
+	if err = provider.Create(Prompt{Name: "waiting", Content: "Before"}); err != nil {
+		t.Fatalf("Failed to create prompt: %v", err)
+	}
+
+	// Another prompter instance is writing the prompt file
+	unlock, err := lockFile(filepath.Join(tempDir, "waiting.md"))
+	if err != nil {
+		t.Fatalf("Failed to take lock: %v", err)
+	}
+
+	updated := make(chan error, 1)
+	go func() {
+		updated <- provider.Update(Prompt{Name: "waiting", Content: "After"})
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+
+	read := make(chan error, 1)
+	go func() {
+		_, err := provider.Read("waiting")
+		read <- err
+	}()
+
+	select {
+	case err = <-read:
+		if err != nil {
+			t.Errorf("Failed to read prompt: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected reading the prompts not to wait for the lock of the prompt file")
+	}
+
+	unlock()
+
+	if err = <-updated; err != nil {
+		t.Errorf("Failed to update prompt: %v", err)
+	}
+}
+
+func TestSavePromptAtomic(t *testing.T) {
+	tempDir := t.TempDir()
+	path := filepath.Join(tempDir, "atomic.md")
+
+	if err := os.WriteFile(path, []byte("---\nname: atomic\n---\nOriginal"), 0600); err != nil {
+		t.Fatalf("Failed to create prompt file: %v", err)
+	}
+
+	if err := savePrompt(Prompt{Id: "atomic", Name: "atomic", Content: "Replaced"}, path); err != nil {
+		t.Fatalf("Failed to save prompt: %v", err)
+	}
+
+	prompt, err := loadPrompt(path, plog.New(filepath.Join(t.TempDir(), "test.log")))
+	if err != nil {
+		t.Fatalf("Failed to load saved prompt: %v", err)
+	}
+	if prompt.Content != "Replaced" {
+		t.Errorf("Expected content 'Replaced', got '%s'", prompt.Content)
+	}
+
+	// Permissions of the replaced file are kept
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected permissions 0600 to be kept, got %v", info.Mode().Perm())
+	}
+
+	// A failed write leaves neither temporary files nor a broken prompt file behind
+	if err = os.Mkdir(filepath.Join(tempDir, "blocked.md"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err = savePrompt(Prompt{Id: "blocked", Name: "blocked", Content: "Lost"}, promptPath(tempDir, "blocked")); err == nil {
+		t.Error("Expected error when a directory is in the way of the prompt file")
+	}
+
+	entries, _ := os.ReadDir(tempDir)
+	if len(entries) != 2 {
+		names := []string{}
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		t.Errorf("Expected only atomic.md and blocked.md, found %v", names)
+	}
+}
+
+func TestFsProviderSharedDirectory(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// Two providers stand in for two prompter instances sharing the directory
+	first, err := NewPromptsFsProvider(tempDir, "")
+	if err != nil {
+		t.Fatalf("Failed to create FsProvider: %v", err)
+	}
+	second, err := NewPromptsFsProvider(tempDir, "")
+	if err != nil {
+		t.Fatalf("Failed to create FsProvider: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+
+	for _, provider := range []*FsProvider{first, second} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- provider.Create(Prompt{Name: "contested", Content: "Content"})
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		if err == nil {
+			succeeded++
+		}
+	}
+
+	if succeeded != 1 {
+		t.Errorf("Expected exactly one instance to create the prompt, %d did", succeeded)
+	}
+
+	entries, _ := os.ReadDir(tempDir)
+	if len(entries) != 1 || entries[0].Name() != "contested.md" {
+		t.Errorf("Expected only contested.md in the directory, found %v", entries)
+	}
+}
@@ -1,16 +1,22 @@
 package promptsdb
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io/fs"
 	"maps"
 	"os"
+	"path"
 	"path/filepath"
-	"regexp"
+	"reflect"
 	"slices"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/hkionline/prompter/internal/plog"
 	"gopkg.in/yaml.v3"
 )
@@ -18,55 +24,131 @@ import (
 type FsProvider struct {
 	mu sync.RWMutex
 	// config
-	cache map[string]Prompt // map of cached prompts identified by prompt id
-	files map[string]string // map of prompt files identified by prompt id
-	dir   string            // directory where prompt files are stored
+	dirs   []*fsDirectory    // prompt directories ordered by precedence, the first one wins on name collisions
+	dir    string            // directory receiving writes, empty when all directories are read-only
+	cache  map[string]Prompt // map of prompts winning the name collisions identified by prompt id
+	files  map[string]string // map of paths of the winning prompt files identified by prompt id
+	logger *plog.Plogger
+	// watcher
+	watcher *fsnotify.Watcher      // watcher for changes made outside of the provider, nil when not watching
+	pending map[string]*time.Timer // debounce timers of changed prompt files identified by file path
+	// listeners notified of changes to the prompts
+	listeners []func(change Change)
+}
+
+// fsDirectory holds the prompts loaded from a single prompts directory
+type fsDirectory struct {
+	path       string
+	precedence int
+	writable   bool
+	cache      map[string]Prompt // map of cached prompts identified by prompt id
+	files      map[string]string // map of prompt files identified by prompt id
 }
 
 type FsProviderConfiguration struct {
-	Directory string `yaml:"prompts_directory"` // directory to save prompt files to
+	Directory   string                     `yaml:"prompts_directory" koanf:"prompts_directory"` // directory to save prompt files to, used when no directories are listed
+	Directories []FsDirectoryConfiguration `yaml:"directories" koanf:"directories"`             // prompt directories to combine
+	Watch       bool                       `yaml:"watch" koanf:"watch"`                         // reload prompt files when they change on disk
+}
+
+type FsDirectoryConfiguration struct {
+	Path       string `yaml:"path" koanf:"path"`             // directory with prompt files
+	Precedence int    `yaml:"precedence" koanf:"precedence"` // on name collisions the prompt from the directory with the highest precedence wins
+	Writable   bool   `yaml:"writable" koanf:"writable"`     // directory receiving created and updated prompts, only one directory can be writable
 }
 
 func NewPromptsFsProvider(promptsDir string, logfile string) (*FsProvider, error) {
 
+	return NewPromptsFsProviderWithDirectories([]FsDirectoryConfiguration{{Path: promptsDir, Writable: true}}, logfile)
+}
+
+// NewPromptsFsProviderWithDirectories sets up a filesystem provider combining several prompt directories
+func NewPromptsFsProviderWithDirectories(directories []FsDirectoryConfiguration, logfile string) (*FsProvider, error) {
+
 	p := plog.New(logfile)
 
 	p.Write(plog.SERVER, "setting up new prompts filesystem provider")
 
-	// Load prompts to cache
-	cache, files, err := loadCache(promptsDir, p)
+	f := &FsProvider{
+		cache:   map[string]Prompt{},
+		files:   map[string]string{},
+		logger:  p,
+		pending: map[string]*time.Timer{},
+	}
+
+	for _, directory := range directories {
 
-	if err != nil {
-		return &FsProvider{}, err
+		if directory.Writable && f.dir != "" {
+			return &FsProvider{}, fmt.Errorf("only one prompts directory can be writable, both %s and %s are", f.dir, directory.Path)
+		}
+
+		// Load prompts to cache
+		cache, files, err := loadCache(directory.Path, p)
+
+		if err != nil {
+			return &FsProvider{}, err
+		}
+
+		for promptId, prompt := range cache {
+			prompt.Source = directory.Path
+			cache[promptId] = prompt
+		}
+
+		if directory.Writable {
+			f.dir = directory.Path
+		}
+
+		f.dirs = append(f.dirs, &fsDirectory{
+			path:       directory.Path,
+			precedence: directory.Precedence,
+			writable:   directory.Writable,
+			cache:      cache,
+			files:      files,
+		})
 	}
 
-	return &FsProvider{
-		cache: cache,
-		files: files,
-		dir:   promptsDir,
-	}, nil
+	// Directories with equal precedence keep their configured order
+	slices.SortStableFunc(f.dirs, func(a, b *fsDirectory) int {
+		return b.precedence - a.precedence
+	})
+
+	for _, dir := range f.dirs {
+		for promptId := range dir.cache {
+			f.resolve(promptId)
+		}
+	}
 
+	return f, nil
 }
 
 func (f *FsProvider) Create(prompt Prompt) error {
 
-	f.mu.Lock()
-	defer f.mu.Unlock()
+	changes := []Change{}
+	defer f.notify(&changes)
 
 	prompt.Id = prompt.Name
 
-	// Write the prompt to a file
-	fileName, err := savePrompt(prompt, f.dir)
+	// Never replace a prompt somebody else has saved with the same name
+	release, err := f.lockPrompt("create", prompt.Id, func() error {
+		if _, ok := f.cache[prompt.Id]; ok {
+			return newError("create", prompt.Id, ErrAlreadyExists)
+		}
+		return nil
+	})
 
 	if err != nil {
 		return err
 	}
 
-	// Add the prompt file to files map
-	f.files[prompt.Id] = fileName
+	defer release()
 
-	// Add the prompt to cache
-	f.cache[prompt.Id] = prompt
+	change, err := f.save("create", prompt, false)
+
+	if err != nil {
+		return err
+	}
+
+	changes = append(changes, change)
 
 	return nil
 }
@@ -81,58 +163,328 @@ func (f *FsProvider) Read(promptId string) (Prompt, error) {
 	if prompt, ok := f.cache[promptId]; ok {
 		return prompt, nil
 	} else {
-		return Prompt{}, errors.New("could not read prompt: no prompt with the given id was found")
+		return Prompt{}, newError("read", promptId, ErrNotFound)
 	}
 }
 
 func (f *FsProvider) Update(prompt Prompt) error {
 
-	f.mu.Lock()
-	defer f.mu.Unlock()
+	changes := []Change{}
+	defer f.notify(&changes)
 
 	prompt.Id = prompt.Name
 
-	// Write the prompt to a file
-	fileName, err := savePrompt(prompt, f.dir)
+	release, err := f.lockPrompt("update", prompt.Id, func() error {
+		if _, ok := f.cache[prompt.Id]; !ok {
+			return newError("update", prompt.Id, ErrNotFound)
+		}
+		return nil
+	})
 
 	if err != nil {
 		return err
 	}
 
-	// Add the prompt file to files map
-	f.files[prompt.Id] = fileName
+	defer release()
 
-	// Add the prompt to cache
-	f.cache[prompt.Id] = prompt
+	change, err := f.save("update", prompt, true)
+
+	if err != nil {
+		return err
+	}
+
+	changes = append(changes, change)
 
 	return nil
 }
 
 func (f *FsProvider) Delete(promptId string) error {
 
-	f.mu.Lock()
-	defer f.mu.Unlock()
+	changes := []Change{}
+	defer f.notify(&changes)
 
-	// Remove the prompt from the cache
-	delete(f.cache, promptId)
+	release, err := f.lockPrompt("delete", promptId, func() error {
+		if _, ok := f.files[promptId]; !ok {
+			return newError("delete", promptId, ErrNotFound)
+		}
+
+		writable, err := f.writableFor("delete", promptId)
+
+		if err != nil {
+			return err
+		}
+
+		// The writable directory only shadows prompts of the directories after it, their files can not be removed
+		if promptFile, ok := writable.files[promptId]; !ok || promptFile == "" {
+			return newError("delete", promptId, fmt.Errorf("%w: it comes from a read-only prompts directory", ErrReadOnly))
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	defer release()
+
+	writable, _ := f.writableFor("delete", promptId)
 
 	// Remove the prompt file
-	if promptFile, ok := f.files[promptId]; ok {
-		return removePrompt(f.dir, promptFile)
-	} else {
-		return errors.New("could not delete prompt: no prompt file with the given id was found")
+	if err = removePrompt(writable.path, writable.files[promptId]); err != nil {
+		return newError("delete", promptId, err)
 	}
 
+	// Remove the prompt from the cache
+	delete(writable.cache, promptId)
+	delete(writable.files, promptId)
+
+	// A prompt of a directory with lower precedence may take its place
+	if change, changed := f.resolve(promptId); changed {
+		changes = append(changes, change)
+	}
+
+	return nil
+}
+
+func (f *FsProvider) List(query PromptQuery) ([]Prompt, int, error) {
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	prompts, total := query.Apply(slices.Collect(maps.Values(f.cache)))
+
+	return prompts, total, nil
 }
 
-func (f *FsProvider) List(query PromptQuery) ([]Prompt, error) {
+// fileOf returns the path of the file the prompt is stored in
+func (f *FsProvider) fileOf(promptId string) (string, bool) {
 
 	f.mu.RLock()
 	defer f.mu.RUnlock()
 
-	return slices.Collect(maps.Values(f.cache)), nil
+	promptFile, ok := f.files[promptId]
+
+	return promptFile, ok
+}
+
+// save writes the prompt to the writable directory, an existing prompt file is replaced
+// only when overwrite is set and the revision of the prompt is current. Callers must hold
+// the lock and the lock of the prompt file, see lockPrompt.
+func (f *FsProvider) save(op string, prompt Prompt, overwrite bool) (Change, error) {
+
+	if err := prompt.Validate(); err != nil {
+		return Change{}, newError(op, prompt.Id, err)
+	}
+
+	writable, err := f.writableFor(op, prompt.Id)
+
+	if err != nil {
+		return Change{}, err
+	}
+
+	// A prompt being updated stays in the file it was loaded from, whatever the file is called
+	promptFile := writable.fileFor(prompt.Id)
+	path := filepath.Join(writable.path, filepath.FromSlash(promptFile))
+
+	if overwrite {
+		// The file may have been changed on disk without the cache knowing about it yet
+		if revision := f.storedRevision(prompt.Id); prompt.Revision != "" && prompt.Revision != revision {
+			return Change{}, newError(op, prompt.Id, fmt.Errorf("%w: expected revision %s, the current revision is %s", ErrConflict, prompt.Revision, revision))
+		}
+	} else if _, err = os.Lstat(path); err == nil {
+		// A file the cache does not know about, e.g. one that failed to parse, is kept as well
+		return Change{}, newError(op, prompt.Id, fmt.Errorf("%w: the prompt file exists already", ErrAlreadyExists))
+	}
+
+	// Write the prompt to a file in the folder of its namespace
+	if err = savePrompt(prompt, path); err != nil {
+		return Change{}, newError(op, prompt.Id, err)
+	}
+
+	promptBytes, err := marshalPrompt(prompt)
+
+	if err != nil {
+		return Change{}, newError(op, prompt.Id, err)
+	}
+
+	prompt.Source = writable.path
+	prompt.Revision = revisionOf(promptBytes)
+
+	// Add the prompt file to files map
+	writable.files[prompt.Id] = promptFile
+
+	// Add the prompt to cache
+	writable.cache[prompt.Id] = prompt
+
+	change, _ := f.resolve(prompt.Id)
+
+	return change, nil
+}
+
+// lockPrompt takes the lock of the file the prompt is written to and only then the provider
+// lock, so that waiting for another prompter instance writing the file does not keep the
+// prompts from being read meanwhile. The check runs before the file is locked and again
+// once the provider lock is held. The returned function releases both locks.
+func (f *FsProvider) lockPrompt(op string, promptId string, check func() error) (func(), error) {
+
+	for {
+		f.mu.RLock()
+		path, err := f.pathOf(op, promptId, check)
+		f.mu.RUnlock()
+
+		if err != nil {
+			return nil, err
+		}
+
+		// Other prompter instances sharing the directory wait until the file is written
+		unlock, err := lockFile(path)
+
+		if err != nil {
+			return nil, newError(op, promptId, err)
+		}
+
+		f.mu.Lock()
+
+		current, err := f.pathOf(op, promptId, check)
+
+		if err == nil && current == path {
+			return func() {
+				f.mu.Unlock()
+				unlock()
+			}, nil
+		}
+
+		f.mu.Unlock()
+		unlock()
+
+		if err != nil {
+			return nil, err
+		}
+
+		// The prompt moved to another file while waiting, e.g. the watcher reloaded it, lock that one
+	}
+}
+
+// pathOf runs the check and returns the path of the file the prompt is written to,
+// callers must hold the lock
+func (f *FsProvider) pathOf(op string, promptId string, check func() error) (string, error) {
+
+	if err := check(); err != nil {
+		return "", err
+	}
+
+	writable, err := f.writableFor(op, promptId)
+
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(writable.path, filepath.FromSlash(writable.fileFor(promptId))), nil
+}
+
+// fileFor returns the slash separated path of the file the prompt is saved to relative to the
+// directory, the file it was loaded from or otherwise a file named after the prompt
+func (d *fsDirectory) fileFor(promptId string) string {
+
+	if promptFile, ok := d.files[promptId]; ok && promptFile != "" {
+		return promptFile
+	}
+
+	return promptId + ".md"
+}
+
+// storedRevision returns the revision of the prompt file currently on disk, callers must hold the lock
+func (f *FsProvider) storedRevision(promptId string) string {
+
+	promptBytes, err := os.ReadFile(f.files[promptId])
+
+	if err != nil {
+		return f.cache[promptId].Revision
+	}
+
+	return revisionOf(promptBytes)
+}
+
+// writableFor returns the writable directory when changes to the prompt would be
+// visible, i.e. the prompt is not shadowed by a read-only directory with higher precedence.
+// Callers must hold the lock.
+func (f *FsProvider) writableFor(op string, promptId string) (*fsDirectory, error) {
+
+	for _, dir := range f.dirs {
+
+		if dir.writable {
+			return dir, nil
+		}
+
+		if _, ok := dir.cache[promptId]; ok {
+			return nil, newError(op, promptId, fmt.Errorf("%w: it comes from the prompts directory %s", ErrReadOnly, dir.path))
+		}
+	}
+
+	return nil, newError(op, promptId, fmt.Errorf("%w: none of the prompts directories is writable", ErrReadOnly))
+}
+
+// resolve updates the prompt in the combined cache from the directory with the highest
+// precedence holding it and reports whether the prompt visible to clients changed.
+// Callers must hold the lock.
+func (f *FsProvider) resolve(promptId string) (Change, bool) {
+
+	previous, existed := f.cache[promptId]
+
+	for _, dir := range f.dirs {
+
+		prompt, ok := dir.cache[promptId]
+
+		if !ok {
+			continue
+		}
+
+		f.cache[promptId] = prompt
+		f.files[promptId] = filepath.Join(dir.path, filepath.FromSlash(dir.files[promptId]))
+
+		if existed {
+			return Change{Type: PROMPT_UPDATED, PromptId: promptId}, !reflect.DeepEqual(previous, prompt)
+		}
+
+		return Change{Type: PROMPT_CREATED, PromptId: promptId}, true
+	}
+
+	delete(f.cache, promptId)
+	delete(f.files, promptId)
+
+	return Change{Type: PROMPT_DELETED, PromptId: promptId}, existed
+}
+
+// Subscribe registers a listener to be called whenever the prompts change
+func (f *FsProvider) Subscribe(listener func(change Change)) {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.listeners = append(f.listeners, listener)
 }
 
+// notify passes the changes to the listeners, it must be called without holding the lock
+func (f *FsProvider) notify(changes *[]Change) {
+
+	if len(*changes) == 0 {
+		return
+	}
+
+	f.mu.RLock()
+	listeners := slices.Clone(f.listeners)
+	f.mu.RUnlock()
+
+	for _, change := range *changes {
+		for _, listener := range listeners {
+			listener(change)
+		}
+	}
+}
+
+// loadCache loads the prompt files of the directory and its subdirectories, the files
+// are identified by their slash separated path relative to the directory
 func loadCache(fromDir string, p *plog.Plogger) (map[string]Prompt, map[string]string, error) {
 
 	p.Write(plog.SERVER, "loading prompts from filesystem to populate the cache")
@@ -140,28 +492,69 @@ func loadCache(fromDir string, p *plog.Plogger) (map[string]Prompt, map[string]s
 	cache := map[string]Prompt{}
 	files := map[string]string{}
 
-	dirEntries, err := os.ReadDir(fromDir)
-
-	if err != nil {
-		return cache, files, err
-	}
+	err := filepath.WalkDir(fromDir, func(path string, entry fs.DirEntry, err error) error {
 
-	for _, entry := range dirEntries {
+		if err != nil {
+			if path == fromDir {
+				return err
+			}
 
-		if !entry.IsDir() {
-			prompt, err := loadPrompt(filepath.Join(fromDir, entry.Name()), p)
+			// Keep loading the rest of the directory tree
+			p.Write(plog.SERVER, err.Error())
+			return nil
+		}
 
-			if err != nil {
-				p.Write(plog.SERVER, err.Error())
-				continue
+		if entry.IsDir() {
+			// Hidden directories such as .git are not part of the prompt library
+			if path != fromDir && strings.HasPrefix(entry.Name(), ".") {
+				return filepath.SkipDir
 			}
+			return nil
+		}
 
-			cache[prompt.Id] = prompt
-			files[prompt.Id] = entry.Name()
+		if filepath.Ext(entry.Name()) != ".md" {
+			return nil
 		}
+
+		promptFile, err := filepath.Rel(fromDir, path)
+
+		if err != nil {
+			return err
+		}
+
+		promptFile = filepath.ToSlash(promptFile)
+
+		prompt, err := loadPrompt(path, p)
+
+		if err != nil {
+			p.Write(plog.SERVER, err.Error())
+			return nil
+		}
+
+		prompt = namespaced(prompt, promptFile)
+
+		cache[prompt.Id] = prompt
+		files[prompt.Id] = promptFile
+
+		return nil
+	})
+
+	return cache, files, err
+}
+
+// namespaced prefixes the prompt name with the folder of the prompt file, so that
+// the prompt in review/security_audit.md is named review/security_audit
+func namespaced(prompt Prompt, promptFile string) Prompt {
+
+	namespace := path.Dir(promptFile)
+
+	if namespace != "." && !strings.HasPrefix(prompt.Name, namespace+"/") {
+		prompt.Name = namespace + "/" + prompt.Name
 	}
 
-	return cache, files, nil
+	prompt.Id = prompt.Name
+
+	return prompt
 }
 
 func loadPrompt(fromFile string, p *plog.Plogger) (Prompt, error) {
@@ -177,62 +570,135 @@ func loadPrompt(fromFile string, p *plog.Plogger) (Prompt, error) {
 		return prompt, err
 	}
 
-	err = yaml.Unmarshal(file, &prompt)
+	frontmatter, content, err := splitFrontmatter(file)
 
 	if err != nil {
-		return prompt, err
+		return prompt, fmt.Errorf("%w: %s: %w", ErrInvalidPrompt, fromFile, err)
 	}
 
-	prompt.Id = prompt.Name
+	// Keys the prompt does not model are kept in Extra
+	err = yaml.Unmarshal(frontmatter, &prompt)
 
-	re, err := regexp.Compile(`(?s)---.*?---\s*(.*)`)
-	match := re.FindStringSubmatch(string(file))
+	if err != nil {
+		return prompt, fmt.Errorf("%w: %s: %w", ErrInvalidPrompt, fromFile, err)
+	}
+
+	err = prompt.validateArguments()
 
 	if err != nil {
-		return prompt, err
+		return prompt, fmt.Errorf("%s: %w", fromFile, err)
 	}
 
-	if len(match) > 1 {
-		// Extract and trim the unstructured text
-		prompt.Content = strings.TrimSpace(match[1])
-	} else {
-		return prompt, fmt.Errorf("failed to extract prompt contents, matches (%d) =< 1", len(match))
+	err = prompt.validateMessages()
+
+	if err != nil {
+		return prompt, fmt.Errorf("%s: %w", fromFile, err)
 	}
 
+	prompt.Id = prompt.Name
+	prompt.Revision = revisionOf(file)
+
+	// Extract and trim the unstructured text
+	prompt.Content = strings.TrimSpace(string(content))
+
 	return prompt, nil
 }
 
-func savePrompt(prompt Prompt, promptDir string) (string, error) {
-
-	path := filepath.Join(promptDir, fmt.Sprintf("%s.%s", prompt.Id, "md"))
+// savePrompt writes the prompt file atomically: the contents go to a temporary file in the
+// same directory which is synced to disk and then renamed over the prompt file. Readers
+// see either the previous or the new prompt file but never a partially written one.
+func savePrompt(prompt Prompt, path string) error {
 
-	file, err := os.Create(path)
-	defer file.Close()
+	promptBytes, err := marshalPrompt(prompt)
 
 	if err != nil {
-		return path, err
+		return err
 	}
 
-	file.WriteString("---\n")
+	if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	// Keep the permissions of a prompt file being replaced
+	mode := os.FileMode(0644)
 
-	promptBytes, err := yaml.Marshal(prompt)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	// The temporary file is hidden and has no .md extension so it is never loaded as a prompt
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
 
 	if err != nil {
-		return path, err
+		return err
 	}
 
-	_, err = file.Write(promptBytes)
+	err = errors.Join(
+		writeAll(file, promptBytes, mode),
+		file.Close(),
+	)
+
+	if err == nil {
+		err = os.Rename(file.Name(), path)
+	}
 
 	if err != nil {
-		return path, err
+		os.Remove(file.Name())
+		return err
+	}
+
+	// Persist the rename, not supported on every platform
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+
+	return nil
+}
+
+// writeAll writes the contents to the file and syncs them to disk
+func writeAll(file *os.File, contents []byte, mode os.FileMode) error {
+
+	if _, err := file.Write(contents); err != nil {
+		return err
+	}
+
+	if err := file.Chmod(mode); err != nil {
+		return err
 	}
 
-	file.WriteString("---\n")
-	file.WriteString(prompt.Content)
+	return file.Sync()
+}
+
+// promptPath gives the path of the file the prompt is saved to
+func promptPath(promptDir string, promptId string) string {
+	return filepath.Join(promptDir, fmt.Sprintf("%s.%s", filepath.FromSlash(promptId), "md"))
+}
+
+// marshalPrompt gives the contents of the prompt file
+func marshalPrompt(prompt Prompt) ([]byte, error) {
+
+	// The folder of the file holds the namespace, the file only keeps the plain name
+	prompt.Name = prompt.Name[strings.LastIndex(prompt.Name, "/")+1:]
+	prompt.Id = prompt.Name
 
-	return path, nil
+	return prompt.Markdown()
+}
+
+// revisionOf identifies a version of a prompt file by its contents
+func revisionOf(promptBytes []byte) string {
+
+	sum := sha256.Sum256(promptBytes)
+
+	return hex.EncodeToString(sum[:8])
 }
 
 func removePrompt(promptDir string, promptFile string) error {
-	return os.Remove(filepath.Join(promptDir, promptFile))
+
+	// An empty file name would remove the prompts directory itself
+	if promptFile == "" {
+		return fmt.Errorf("no prompt file to remove in %s", promptDir)
+	}
+
+	return os.Remove(filepath.Join(promptDir, filepath.FromSlash(promptFile)))
 }
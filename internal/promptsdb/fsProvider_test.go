@@ -1,9 +1,11 @@
 package promptsdb
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -52,7 +54,7 @@ func TestFsProviderCreate(t *testing.T) {
 		Name:        "test-prompt",
 		Title:       "Test Prompt",
 		Description: "A test prompt for unit testing",
-		Arguments:   []string{"name", "age"},
+		Arguments:   []Argument{{Name: "name"}, {Name: "age"}},
 		Content:     "Hello {{.name}}, you are {{.age}} years old.",
 		Tags:        []string{"test", "example"},
 	}
@@ -114,7 +116,7 @@ func TestFsProviderCreateWithSpecialCharacters(t *testing.T) {
 		Name:        "test-prompt_123",
 		Title:       "Test Prompt With Special Chars!",
 		Description: "A test prompt with special chars @#$%",
-		Arguments:   []string{"name", "age"},
+		Arguments:   []Argument{{Name: "name"}, {Name: "age"}},
 		Content:     "Hello {{.name}}, you are {{.age}} years old.",
 		Tags:        []string{"test", "example"},
 	}
@@ -176,7 +178,7 @@ func TestFsProviderUpdate(t *testing.T) {
 		Name:        "test-prompt",
 		Title:       "Test Prompt",
 		Description: "A test prompt for unit testing",
-		Arguments:   []string{"name", "age"},
+		Arguments:   []Argument{{Name: "name"}, {Name: "age"}},
 		Content:     "Hello {{.name}}, you are {{.age}} years old.",
 		Tags:        []string{"test", "example"},
 	}
@@ -210,6 +212,44 @@ func TestFsProviderUpdate(t *testing.T) {
 	}
 }
 
+func TestFsProviderUpdateKeepsFile(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// The file is not named after the prompt it holds
+	if err := os.WriteFile(filepath.Join(tempDir, "other.md"), []byte("---\nname: y\n---\nOriginal"), 0644); err != nil {
+		t.Fatalf("Failed to create prompt file: %v", err)
+	}
+
+	provider, err := NewPromptsFsProvider(tempDir, filepath.Join(tempDir, "test.log"))
+	if err != nil {
+		t.Fatalf("Failed to create FsProvider: %v", err)
+	}
+
+	if err = provider.Update(Prompt{Name: "y", Content: "Updated"}); err != nil {
+		t.Fatalf("Failed to update prompt: %v", err)
+	}
+
+	if _, err = os.Stat(filepath.Join(tempDir, "y.md")); !os.IsNotExist(err) {
+		t.Error("Expected no y.md to be created next to the file the prompt was loaded from")
+	}
+
+	prompt, err := loadPrompt(filepath.Join(tempDir, "other.md"), plog.New(filepath.Join(tempDir, "test.log")))
+	if err != nil {
+		t.Fatalf("Failed to load updated prompt: %v", err)
+	}
+	if prompt.Content != "Updated" {
+		t.Errorf("Expected other.md to hold the update, got '%s'", prompt.Content)
+	}
+
+	if err = provider.Delete("y"); err != nil {
+		t.Fatalf("Failed to delete prompt: %v", err)
+	}
+
+	if _, err = os.Stat(filepath.Join(tempDir, "other.md")); !os.IsNotExist(err) {
+		t.Error("Expected other.md to be removed with the prompt")
+	}
+}
+
 func TestFsProviderList(t *testing.T) {
 	// Create a temporary directory for testing
 	tempDir, err := os.MkdirTemp("", "test_prompts_list")
@@ -251,7 +291,7 @@ func TestFsProviderList(t *testing.T) {
 	}
 
 	// List all prompts
-	prompts, err := provider.List(PromptQuery{})
+	prompts, _, err := provider.List(PromptQuery{})
 	if err != nil {
 		t.Fatalf("Failed to list prompts: %v", err)
 	}
@@ -315,7 +355,7 @@ func TestFsProviderListWithQuery(t *testing.T) {
 	}
 
 	// Test with query that should return all
-	prompts, err := provider.List(PromptQuery{All: true})
+	prompts, _, err := provider.List(PromptQuery{All: true})
 	if err != nil {
 		t.Fatalf("Failed to list prompts with query: %v", err)
 	}
@@ -325,7 +365,7 @@ func TestFsProviderListWithQuery(t *testing.T) {
 	}
 
 	// Test with empty query (should also return all)
-	prompts, err = provider.List(PromptQuery{})
+	prompts, _, err = provider.List(PromptQuery{})
 	if err != nil {
 		t.Fatalf("Failed to list prompts with empty query: %v", err)
 	}
@@ -335,6 +375,456 @@ func TestFsProviderListWithQuery(t *testing.T) {
 	}
 }
 
+func TestFsProviderListFilters(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "test_prompts_list_filters")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Create provider
+	provider, err := NewPromptsFsProvider(tempDir, "")
+	if err != nil {
+		t.Fatalf("Failed to create FsProvider: %v", err)
+	}
+
+	for _, name := range []string{"review_code", "describe_tampere", "review_docs", "daily_report"} {
+		err = provider.Create(Prompt{Name: name, Content: "Content for " + name})
+		if err != nil {
+			t.Fatalf("Failed to create prompt %s: %v", name, err)
+		}
+	}
+
+	// Results should be sorted by name
+	prompts, total, err := provider.List(PromptQuery{})
+	if err != nil {
+		t.Fatalf("Failed to list prompts: %v", err)
+	}
+
+	expected := []string{"daily_report", "describe_tampere", "review_code", "review_docs"}
+	if total != len(expected) {
+		t.Errorf("Expected total %d, got %d", len(expected), total)
+	}
+	for i, name := range expected {
+		if prompts[i].Name != name {
+			t.Errorf("Expected prompt %d to be %s, got %s", i, name, prompts[i].Name)
+		}
+	}
+
+	// Prefix filter
+	prompts, total, err = provider.List(PromptQuery{NameStartsWith: "review"})
+	if err != nil {
+		t.Fatalf("Failed to list prompts with prefix: %v", err)
+	}
+
+	if total != 2 || len(prompts) != 2 {
+		t.Errorf("Expected 2 prompts starting with 'review', got %d (total %d)", len(prompts), total)
+	}
+
+	// Substring filter combined with prefix filter
+	prompts, total, err = provider.List(PromptQuery{NameStartsWith: "d", NameContains: "tam"})
+	if err != nil {
+		t.Fatalf("Failed to list prompts with substring: %v", err)
+	}
+
+	if total != 1 || len(prompts) != 1 || prompts[0].Name != "describe_tampere" {
+		t.Errorf("Expected only describe_tampere, got %v (total %d)", prompts, total)
+	}
+
+	// All ignores the filters
+	prompts, total, err = provider.List(PromptQuery{All: true, NameStartsWith: "review", IndexTo: 1})
+	if err != nil {
+		t.Fatalf("Failed to list all prompts: %v", err)
+	}
+
+	if total != 4 || len(prompts) != 4 {
+		t.Errorf("Expected All to return 4 prompts, got %d (total %d)", len(prompts), total)
+	}
+}
+
+func TestFsProviderListPagination(t *testing.T) {
+	// Create a temporary directory for testing
+	tempDir, err := os.MkdirTemp("", "test_prompts_list_pagination")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Create provider
+	provider, err := NewPromptsFsProvider(tempDir, "")
+	if err != nil {
+		t.Fatalf("Failed to create FsProvider: %v", err)
+	}
+
+	for i := range 5 {
+		err = provider.Create(Prompt{Name: fmt.Sprintf("prompt-%d", i), Content: "Content"})
+		if err != nil {
+			t.Fatalf("Failed to create prompt %d: %v", i, err)
+		}
+	}
+
+	tests := []struct {
+		query    PromptQuery
+		expected []string
+	}{
+		{PromptQuery{IndexFrom: 0, IndexTo: 2}, []string{"prompt-0", "prompt-1"}},
+		{PromptQuery{IndexFrom: 2, IndexTo: 4}, []string{"prompt-2", "prompt-3"}},
+		{PromptQuery{IndexFrom: 4, IndexTo: 6}, []string{"prompt-4"}},
+		{PromptQuery{IndexFrom: 3}, []string{"prompt-3", "prompt-4"}},
+		{PromptQuery{IndexFrom: 10, IndexTo: 12}, []string{}},
+		{PromptQuery{IndexFrom: 3, IndexTo: 1}, []string{}},
+	}
+
+	for _, test := range tests {
+		prompts, total, err := provider.List(test.query)
+		if err != nil {
+			t.Fatalf("Failed to list prompts with query %+v: %v", test.query, err)
+		}
+
+		if total != 5 {
+			t.Errorf("Expected total 5 for query %+v, got %d", test.query, total)
+		}
+
+		if len(prompts) != len(test.expected) {
+			t.Errorf("Expected %d prompts for query %+v, got %d", len(test.expected), test.query, len(prompts))
+			continue
+		}
+
+		for i, name := range test.expected {
+			if prompts[i].Name != name {
+				t.Errorf("Expected prompt %s at index %d for query %+v, got %s", name, i, test.query, prompts[i].Name)
+			}
+		}
+	}
+}
+
+func TestFsProviderCreateExisting(t *testing.T) {
+	tempDir := t.TempDir()
+
+	provider, err := NewPromptsFsProvider(tempDir, "")
+	if err != nil {
+		t.Fatalf("Failed to create FsProvider: %v", err)
+	}
+
+	if err = provider.Create(Prompt{Name: "taken", Content: "Original"}); err != nil {
+		t.Fatalf("Failed to create prompt: %v", err)
+	}
+
+	if err = provider.Create(Prompt{Name: "taken", Content: "Clobbered"}); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("Expected ErrAlreadyExists when creating an existing prompt, got %v", err)
+	}
+
+	if prompt, _ := provider.Read("taken"); prompt.Content != "Original" {
+		t.Errorf("Expected the original prompt to be kept, got %s", prompt.Content)
+	}
+
+	// Files the cache does not hold are not replaced either
+	brokenFile := filepath.Join(tempDir, "broken.md")
+	if err = os.WriteFile(brokenFile, []byte("no frontmatter yet"), 0644); err != nil {
+		t.Fatalf("Failed to write prompt file: %v", err)
+	}
+
+	if err = provider.Create(Prompt{Name: "broken", Content: "Clobbered"}); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("Expected ErrAlreadyExists when a prompt file exists, got %v", err)
+	}
+
+	if content, _ := os.ReadFile(brokenFile); string(content) != "no frontmatter yet" {
+		t.Errorf("Expected the existing file to be kept, got %s", content)
+	}
+}
+
+func TestFsProviderUpdateRevision(t *testing.T) {
+	tempDir := t.TempDir()
+
+	provider, err := NewPromptsFsProvider(tempDir, "")
+	if err != nil {
+		t.Fatalf("Failed to create FsProvider: %v", err)
+	}
+
+	if err = provider.Update(Prompt{Name: "missing"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound when updating a missing prompt, got %v", err)
+	}
+	if _, err = os.Stat(filepath.Join(tempDir, "missing.md")); !os.IsNotExist(err) {
+		t.Error("Expected no prompt file to be created by a failed update")
+	}
+
+	if err = provider.Create(Prompt{Name: "versioned", Content: "First"}); err != nil {
+		t.Fatalf("Failed to create prompt: %v", err)
+	}
+
+	first, _ := provider.Read("versioned")
+	if first.Revision == "" {
+		t.Fatal("Expected created prompt to have a revision")
+	}
+
+	// A reloaded provider agrees on the revision
+	reloaded, err := NewPromptsFsProvider(tempDir, "")
+	if err != nil {
+		t.Fatalf("Failed to create FsProvider: %v", err)
+	}
+	if prompt, _ := reloaded.Read("versioned"); prompt.Revision != first.Revision {
+		t.Errorf("Expected revision %s after reload, got %s", first.Revision, prompt.Revision)
+	}
+
+	first.Content = "Second"
+	if err = provider.Update(first); err != nil {
+		t.Fatalf("Failed to update prompt with the current revision: %v", err)
+	}
+
+	second, _ := provider.Read("versioned")
+	if second.Revision == first.Revision {
+		t.Error("Expected the revision to change with the content")
+	}
+
+	// Writing over a newer revision is rejected
+	first.Content = "Stale"
+	if err = provider.Update(first); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected ErrConflict when updating a stale revision, got %v", err)
+	}
+
+	// So is writing over a change made on disk the cache has not seen
+	err = os.WriteFile(filepath.Join(tempDir, "versioned.md"), []byte("---\nname: versioned\n---\nEdited by hand"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to edit prompt file: %v", err)
+	}
+
+	second.Content = "Third"
+	if err = provider.Update(second); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected ErrConflict when the file changed on disk, got %v", err)
+	}
+
+	// Without a revision the update is unconditional
+	if err = provider.Update(Prompt{Name: "versioned", Content: "Forced"}); err != nil {
+		t.Errorf("Expected update without a revision to succeed, got %v", err)
+	}
+}
+
+func TestFsProviderSubscribe(t *testing.T) {
+	provider, err := NewPromptsFsProvider(t.TempDir(), "")
+	if err != nil {
+		t.Fatalf("Failed to create FsProvider: %v", err)
+	}
+
+	changes := []Change{}
+	provider.Subscribe(func(change Change) {
+		// Listeners are called outside of the lock so reading is allowed
+		provider.List(PromptQuery{})
+		changes = append(changes, change)
+	})
+
+	if err = provider.Create(Prompt{Name: "subscribed", Content: "Content"}); err != nil {
+		t.Fatalf("Failed to create prompt: %v", err)
+	}
+	if err = provider.Update(Prompt{Name: "subscribed", Content: "Changed"}); err != nil {
+		t.Fatalf("Failed to update prompt: %v", err)
+	}
+	if err = provider.Delete("subscribed"); err != nil {
+		t.Fatalf("Failed to delete prompt: %v", err)
+	}
+
+	expected := []Change{
+		{Type: PROMPT_CREATED, PromptId: "subscribed"},
+		{Type: PROMPT_UPDATED, PromptId: "subscribed"},
+		{Type: PROMPT_DELETED, PromptId: "subscribed"},
+	}
+
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected %d changes, got %d: %v", len(expected), len(changes), changes)
+	}
+
+	for i, change := range expected {
+		if changes[i] != change {
+			t.Errorf("Expected change %v at index %d, got %v", change, i, changes[i])
+		}
+	}
+}
+
+// newTestDirectories sets up a read-only team directory and a writable personal
+// directory, both holding a prompt named shared
+func newTestDirectories(t *testing.T) (string, string) {
+	t.Helper()
+
+	teamDir := t.TempDir()
+	personalDir := t.TempDir()
+
+	files := map[string]string{
+		filepath.Join(teamDir, "shared.md"):       "---\nname: shared\ntitle: Team\n---\nTeam content",
+		filepath.Join(teamDir, "team_only.md"):    "---\nname: team_only\n---\nTeam only content",
+		filepath.Join(personalDir, "shared.md"):   "---\nname: shared\ntitle: Personal\n---\nPersonal content",
+		filepath.Join(personalDir, "personal.md"): "---\nname: personal\n---\nPersonal only content",
+	}
+
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write prompt file %s: %v", path, err)
+		}
+	}
+
+	return teamDir, personalDir
+}
+
+func TestFsProviderMultipleDirectories(t *testing.T) {
+	teamDir, personalDir := newTestDirectories(t)
+
+	tests := []struct {
+		directories []FsDirectoryConfiguration
+		source      string
+		title       string
+	}{
+		// The highest precedence wins
+		{[]FsDirectoryConfiguration{{Path: teamDir, Precedence: 10}, {Path: personalDir, Writable: true}}, teamDir, "Team"},
+		{[]FsDirectoryConfiguration{{Path: teamDir}, {Path: personalDir, Precedence: 10, Writable: true}}, personalDir, "Personal"},
+		// Equal precedence keeps the configured order
+		{[]FsDirectoryConfiguration{{Path: personalDir, Writable: true}, {Path: teamDir}}, personalDir, "Personal"},
+	}
+
+	for _, test := range tests {
+		provider, err := NewPromptsFsProviderWithDirectories(test.directories, "")
+		if err != nil {
+			t.Fatalf("Failed to create FsProvider: %v", err)
+		}
+
+		if provider.dir != personalDir {
+			t.Errorf("Expected writable dir %s, got %s", personalDir, provider.dir)
+		}
+
+		prompt, err := provider.Read("shared")
+		if err != nil {
+			t.Fatalf("Failed to read prompt: %v", err)
+		}
+		if prompt.Source != test.source || prompt.Title != test.title {
+			t.Errorf("Expected shared prompt %s from %s, got %s from %s", test.title, test.source, prompt.Title, prompt.Source)
+		}
+
+		prompts, total, err := provider.List(PromptQuery{})
+		if err != nil {
+			t.Fatalf("Failed to list prompts: %v", err)
+		}
+		if total != 3 {
+			t.Errorf("Expected 3 prompts, got %d: %v", total, prompts)
+		}
+
+		for _, prompt := range prompts {
+			if prompt.Source == "" {
+				t.Errorf("Expected listed prompt %s to report its source", prompt.Name)
+			}
+		}
+	}
+}
+
+func TestFsProviderMultipleDirectoriesWrites(t *testing.T) {
+	teamDir, personalDir := newTestDirectories(t)
+
+	provider, err := NewPromptsFsProviderWithDirectories([]FsDirectoryConfiguration{
+		{Path: teamDir, Precedence: 10},
+		{Path: personalDir, Writable: true},
+	}, "")
+	if err != nil {
+		t.Fatalf("Failed to create FsProvider: %v", err)
+	}
+
+	// New prompts go to the writable directory
+	if err = provider.Create(Prompt{Name: "created", Content: "Created"}); err != nil {
+		t.Fatalf("Failed to create prompt: %v", err)
+	}
+	if _, err = os.Stat(filepath.Join(personalDir, "created.md")); err != nil {
+		t.Errorf("Expected prompt file in the writable directory: %v", err)
+	}
+	if prompt, _ := provider.Read("created"); prompt.Source != personalDir {
+		t.Errorf("Expected source %s, got %s", personalDir, prompt.Source)
+	}
+
+	// Prompts of a read-only directory with higher precedence are not changed
+	if err = provider.Update(Prompt{Name: "team_only", Content: "Changed"}); err == nil {
+		t.Error("Expected error when updating a prompt of a read-only directory")
+	}
+	if err = provider.Delete("shared"); err == nil {
+		t.Error("Expected error when deleting a prompt of a read-only directory")
+	}
+	if _, err = os.Stat(filepath.Join(teamDir, "shared.md")); err != nil {
+		t.Errorf("Expected read-only prompt file to be kept: %v", err)
+	}
+}
+
+func TestFsProviderDeleteRevealsLowerPrecedence(t *testing.T) {
+	teamDir, personalDir := newTestDirectories(t)
+
+	provider, err := NewPromptsFsProviderWithDirectories([]FsDirectoryConfiguration{
+		{Path: teamDir},
+		{Path: personalDir, Precedence: 10, Writable: true},
+	}, "")
+	if err != nil {
+		t.Fatalf("Failed to create FsProvider: %v", err)
+	}
+
+	changes := []Change{}
+	provider.Subscribe(func(change Change) {
+		changes = append(changes, change)
+	})
+
+	if err = provider.Delete("shared"); err != nil {
+		t.Fatalf("Failed to delete prompt: %v", err)
+	}
+
+	prompt, err := provider.Read("shared")
+	if err != nil {
+		t.Fatalf("Expected the team prompt to take the place of the deleted one: %v", err)
+	}
+	if prompt.Source != teamDir || prompt.Content != "Team content" {
+		t.Errorf("Expected team prompt, got %+v", prompt)
+	}
+
+	if len(changes) != 1 || changes[0] != (Change{Type: PROMPT_UPDATED, PromptId: "shared"}) {
+		t.Errorf("Expected a single update, got %v", changes)
+	}
+}
+
+func TestFsProviderDeleteLowerPrecedenceReadOnly(t *testing.T) {
+	teamDir, personalDir := newTestDirectories(t)
+
+	provider, err := NewPromptsFsProviderWithDirectories([]FsDirectoryConfiguration{
+		{Path: teamDir},
+		{Path: personalDir, Precedence: 10, Writable: true},
+	}, "")
+	if err != nil {
+		t.Fatalf("Failed to create FsProvider: %v", err)
+	}
+
+	// The writable directory does not hold the prompt, so there is nothing it can remove
+	if err = provider.Delete("team_only"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly when deleting a prompt of a read-only directory, got %v", err)
+	}
+
+	if _, err = os.Stat(personalDir); err != nil {
+		t.Errorf("Expected the writable directory to be kept: %v", err)
+	}
+	if _, err = os.Stat(filepath.Join(teamDir, "team_only.md")); err != nil {
+		t.Errorf("Expected read-only prompt file to be kept: %v", err)
+	}
+	if _, err = provider.Read("team_only"); err != nil {
+		t.Errorf("Expected the prompt to still be readable: %v", err)
+	}
+}
+
+func TestNewPromptsFsProviderWithDirectoriesErrors(t *testing.T) {
+	_, err := NewPromptsFsProviderWithDirectories([]FsDirectoryConfiguration{
+		{Path: t.TempDir(), Writable: true},
+		{Path: t.TempDir(), Writable: true},
+	}, "")
+	if err == nil {
+		t.Error("Expected error when more than one directory is writable")
+	}
+
+	provider, err := NewPromptsFsProviderWithDirectories([]FsDirectoryConfiguration{{Path: t.TempDir()}}, "")
+	if err != nil {
+		t.Fatalf("Failed to create FsProvider: %v", err)
+	}
+	if err = provider.Create(Prompt{Name: "nowhere"}); err == nil {
+		t.Error("Expected error when creating a prompt without a writable directory")
+	}
+}
+
 func TestConcurrencySafety(t *testing.T) {
 	// Create a temporary directory for testing
 	tempDir, err := os.MkdirTemp("", "test_concurrency")
@@ -354,7 +844,7 @@ func TestConcurrencySafety(t *testing.T) {
 		Name:        "concurrent-prompt",
 		Title:       "Concurrent Test Prompt",
 		Description: "A test prompt for concurrent testing",
-		Arguments:   []string{"name"},
+		Arguments:   []Argument{{Name: "name"}},
 		Content:     "Hello {{.name}}!",
 		Tags:        []string{"test"},
 	}
@@ -395,7 +885,7 @@ func TestEmptyAndNilFields(t *testing.T) {
 		Name:        "empty-fields-prompt",
 		Title:       "",
 		Description: "",
-		Arguments:   []string{},
+		Arguments:   []Argument{},
 		Content:     "Hello world!",
 		Tags:        []string{},
 	}
@@ -439,7 +929,7 @@ func TestPromptWithNoArguments(t *testing.T) {
 		Name:        "no-args-prompt",
 		Title:       "No Arguments Prompt",
 		Description: "A prompt with no arguments",
-		Arguments:   []string{},
+		Arguments:   []Argument{},
 		Content:     "Hello world!",
 		Tags:        []string{"test"},
 	}
@@ -484,7 +974,7 @@ func TestFilePermissions(t *testing.T) {
 		Name:        "permission-test",
 		Title:       "Permission Test Prompt",
 		Description: "A test prompt for permission testing",
-		Arguments:   []string{"name"},
+		Arguments:   []Argument{{Name: "name"}},
 		Content:     "Hello {{.name}}!",
 		Tags:        []string{"test"},
 	}
@@ -582,9 +1072,13 @@ func TestFsProviderDeleteNonexistent(t *testing.T) {
 		t.Error("Expected error when deleting non-existent prompt")
 	}
 
-	// Verify error message
-	if !strings.Contains(err.Error(), "no prompt file with the given id was found") {
-		t.Errorf("Expected specific error message, got: %v", err)
+	// Verify error
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got: %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "nonexistent-prompt") {
+		t.Errorf("Expected error message to name the prompt, got: %v", err)
 	}
 }
 
@@ -646,6 +1140,87 @@ Hello {{.name}}, you are {{.age}} years old.`
 	}
 }
 
+func TestLoadPromptArgumentDetails(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// Plain argument names and argument mappings can be mixed
+	promptFile := filepath.Join(tempDir, "review.md")
+	promptContent := `---
+name: review
+arguments:
+  - language
+  - name: code
+    description: The code to review
+    required: true
+  - name: depth
+    values: [quick, thorough]
+    examples:
+      - quick
+---
+Review this {{.language}} code: {{.code}}`
+
+	err := os.WriteFile(promptFile, []byte(promptContent), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create prompt file: %v", err)
+	}
+
+	prompt, err := loadPrompt(promptFile, plog.New(filepath.Join(tempDir, "test.log")))
+	if err != nil {
+		t.Fatalf("Failed to load prompt: %v", err)
+	}
+
+	expected := []Argument{
+		{Name: "language"},
+		{Name: "code", Description: "The code to review", Required: true},
+		{Name: "depth", Values: []string{"quick", "thorough"}, Examples: []string{"quick"}},
+	}
+
+	if len(prompt.Arguments) != len(expected) {
+		t.Fatalf("Expected %d arguments, got %d", len(expected), len(prompt.Arguments))
+	}
+
+	for i, argument := range expected {
+		if !reflect.DeepEqual(prompt.Arguments[i], argument) {
+			t.Errorf("Expected argument %+v, got %+v", argument, prompt.Arguments[i])
+		}
+	}
+
+	if required := prompt.RequiredArguments(); len(required) != 1 || required[0] != "code" {
+		t.Errorf("Expected only 'code' to be required, got %v", required)
+	}
+
+	// Saving keeps plain arguments plain and details as mappings
+	prompt.Id = "review-saved"
+	savedPath := promptPath(tempDir, prompt.Id)
+	if err := savePrompt(prompt, savedPath); err != nil {
+		t.Fatalf("Failed to save prompt: %v", err)
+	}
+
+	content, err := os.ReadFile(savedPath)
+	if err != nil {
+		t.Fatalf("Failed to read saved file: %v", err)
+	}
+
+	if !strings.Contains(string(content), "- language\n") {
+		t.Errorf("Expected plain argument to be saved as a name, got:\n%s", content)
+	}
+
+	if !strings.Contains(string(content), "required: true") {
+		t.Errorf("Expected required flag to be saved, got:\n%s", content)
+	}
+
+	reloaded, err := loadPrompt(savedPath, plog.New(filepath.Join(tempDir, "test.log")))
+	if err != nil {
+		t.Fatalf("Failed to reload saved prompt: %v", err)
+	}
+
+	for i, argument := range expected {
+		if !reflect.DeepEqual(reloaded.Arguments[i], argument) {
+			t.Errorf("Expected reloaded argument %+v, got %+v", argument, reloaded.Arguments[i])
+		}
+	}
+}
+
 func TestLoadPromptMalformedYAML(t *testing.T) {
 	// Create a temporary directory for testing
 	tempDir, err := os.MkdirTemp("", "test_load_prompt_malformed")
@@ -869,44 +1444,142 @@ func TestLoadCacheEmptyDirectory(t *testing.T) {
 }
 
 func TestLoadCacheWithSubdirectories(t *testing.T) {
-	// Create a temporary directory for testing
-	tempDir, err := os.MkdirTemp("", "test_load_cache_subdirs")
+	tempDir := t.TempDir()
+
+	files := map[string]string{
+		"valid.md":                     "---\nname: valid-prompt\n---\nValid content",
+		"review/security_audit.md":     "---\nname: security_audit\n---\nAudit content",
+		"review/deep/nested.md":        "---\nname: nested\n---\nNested content",
+		"docs/docs/already.md":         "---\nname: docs/docs/already\n---\nPrefixed content",
+		"review/notes.txt":             "Not a prompt",
+		".git/hidden.md":               "---\nname: hidden\n---\nHidden content",
+		"review/.drafts/unfinished.md": "---\nname: unfinished\n---\nDraft content",
+	}
+
+	for file, content := range files {
+		path := filepath.Join(tempDir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create folder for %s: %v", file, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create prompt file %s: %v", file, err)
+		}
+	}
+
+	cache, promptFiles, err := loadCache(tempDir, plog.New(filepath.Join(t.TempDir(), "test.log")))
 	if err != nil {
-		t.Fatalf("Failed to create temp dir: %v", err)
+		t.Fatalf("Failed to load cache: %v", err)
 	}
-	defer os.RemoveAll(tempDir)
 
-	// Create a valid prompt file
-	validPrompt := `---
-name: valid-prompt
-title: Valid Prompt
----
-Valid content`
-	err = os.WriteFile(filepath.Join(tempDir, "valid.md"), []byte(validPrompt), 0644)
+	// Folders become namespaces, hidden folders and other files are skipped
+	expected := map[string]string{
+		"valid-prompt":          "valid.md",
+		"review/security_audit": "review/security_audit.md",
+		"review/deep/nested":    "review/deep/nested.md",
+		"docs/docs/already":     "docs/docs/already.md",
+	}
+
+	if len(cache) != len(expected) {
+		t.Errorf("Expected %d prompts in cache, got %d: %v", len(expected), len(cache), promptFiles)
+	}
+
+	for promptId, file := range expected {
+		if prompt, ok := cache[promptId]; !ok || prompt.Name != promptId {
+			t.Errorf("Expected prompt %s in cache, got %+v", promptId, prompt)
+		}
+		if promptFiles[promptId] != file {
+			t.Errorf("Expected prompt %s in file %s, got %s", promptId, file, promptFiles[promptId])
+		}
+	}
+}
+
+func TestFsProviderNamespaces(t *testing.T) {
+	tempDir := t.TempDir()
+
+	provider, err := NewPromptsFsProvider(tempDir, "")
 	if err != nil {
-		t.Fatalf("Failed to create valid prompt file: %v", err)
+		t.Fatalf("Failed to create FsProvider: %v", err)
 	}
 
-	// Create a subdirectory (should be ignored)
-	subDir := filepath.Join(tempDir, "subdir")
-	err = os.Mkdir(subDir, 0755)
+	if err = provider.Create(Prompt{Name: "review/security_audit", Content: "Audit"}); err != nil {
+		t.Fatalf("Failed to create namespaced prompt: %v", err)
+	}
+
+	// The prompt is saved to the folder of its namespace under its plain name
+	promptFile := filepath.Join(tempDir, "review", "security_audit.md")
+	content, err := os.ReadFile(promptFile)
 	if err != nil {
-		t.Fatalf("Failed to create subdirectory: %v", err)
+		t.Fatalf("Expected prompt file in the namespace folder: %v", err)
+	}
+	if !strings.Contains(string(content), "name: security_audit\n") {
+		t.Errorf("Expected plain name in the frontmatter, got:\n%s", content)
 	}
 
-	// Create a mock logger
-	logFile := filepath.Join(tempDir, "test.log")
-	plogger := plog.New(logFile)
+	if err = provider.Update(Prompt{Name: "review/security_audit", Content: "Changed"}); err != nil {
+		t.Fatalf("Failed to update namespaced prompt: %v", err)
+	}
 
-	// Load cache - should skip subdirectories
-	cache, _, err := loadCache(tempDir, plogger)
+	// Reloading gives back the same namespaced prompt
+	reloaded, err := NewPromptsFsProvider(tempDir, "")
 	if err != nil {
-		t.Fatalf("Failed to load cache: %v", err)
+		t.Fatalf("Failed to create FsProvider: %v", err)
 	}
 
-	// Should only load the valid file, ignoring the subdirectory
-	if len(cache) != 1 {
-		t.Errorf("Expected 1 prompt in cache, got %d", len(cache))
+	prompt, err := reloaded.Read("review/security_audit")
+	if err != nil {
+		t.Fatalf("Failed to read namespaced prompt: %v", err)
+	}
+	if prompt.Content != "Changed" || prompt.Namespace() != "review" {
+		t.Errorf("Expected updated prompt in namespace review, got %+v", prompt)
+	}
+
+	if err = reloaded.Delete("review/security_audit"); err != nil {
+		t.Fatalf("Failed to delete namespaced prompt: %v", err)
+	}
+	if _, err = os.Stat(promptFile); !os.IsNotExist(err) {
+		t.Error("Expected namespaced prompt file to be removed")
+	}
+
+	for _, name := range []string{"", "../escape", "/absolute", "review//double", "review/", ".hidden/prompt", `review\windows`} {
+		if err = provider.Create(Prompt{Name: name}); err == nil {
+			t.Errorf("Expected error when creating a prompt named %q", name)
+		}
+	}
+}
+
+func TestPromptQueryNamespace(t *testing.T) {
+	prompts := []Prompt{
+		{Name: "review/security_audit"},
+		{Name: "review/deep/nested"},
+		{Name: "reviewer/other"},
+		{Name: "review"},
+		{Name: "docs/readme"},
+	}
+
+	tests := []struct {
+		namespace string
+		expected  []string
+	}{
+		{"", []string{"docs/readme", "review", "review/deep/nested", "review/security_audit", "reviewer/other"}},
+		{"review", []string{"review/deep/nested", "review/security_audit"}},
+		{"review/", []string{"review/deep/nested", "review/security_audit"}},
+		{"review/deep", []string{"review/deep/nested"}},
+		{"ops", []string{}},
+	}
+
+	for _, test := range tests {
+		results, total := PromptQuery{Namespace: test.namespace}.Apply(prompts)
+
+		if total != len(test.expected) {
+			t.Errorf("Expected %d prompts in namespace %q, got %d", len(test.expected), test.namespace, total)
+			continue
+		}
+
+		for i, name := range test.expected {
+			if results[i].Name != name {
+				t.Errorf("Expected prompt %s at index %d in namespace %q, got %s", name, i, test.namespace, results[i].Name)
+			}
+		}
 	}
 }
 
@@ -1002,24 +1675,97 @@ func TestNewWithDefaultProvider(t *testing.T) {
 
 	logFile := filepath.Join(tempDir, "test.log")
 
-	// Should default to filesystem provider
-	provider, err := New("unknown-provider", config, logFile)
+	// An unknown provider is refused instead of falling back to the filesystem
+	if _, err = New("unknown-provider", config, logFile); err == nil {
+		t.Error("Expected error for an unknown provider")
+	}
+
+	// Should default to filesystem provider when none is given
+	provider, err := New("", config, logFile)
 	if err != nil {
 		t.Fatalf("Failed to create provider: %v", err)
 	}
 
-	if provider == nil {
-		t.Fatal("Expected non-nil provider")
+	if _, ok := provider.(*FsProvider); !ok {
+		t.Errorf("Expected filesystem provider, got %T", provider)
 	}
 }
 
 func TestPromptsDBError(t *testing.T) {
-	// Test the PromptsDBError type
-	err := &PromptsDBError{}
+	tests := []struct {
+		err      *PromptsDBError
+		expected string
+	}{
+		{&PromptsDBError{Op: "read", PromptId: "review/code", Err: ErrNotFound}, "could not read prompt review/code: prompt not found"},
+		{&PromptsDBError{Op: "create", Err: fmt.Errorf("%w: the name is empty", ErrInvalidPrompt)}, "could not create prompt: invalid prompt: the name is empty"},
+		{&PromptsDBError{Op: "list"}, "could not list prompt"},
+	}
+
+	for _, test := range tests {
+		if test.err.Error() != test.expected {
+			t.Errorf("Expected error string '%s', got '%s'", test.expected, test.err.Error())
+		}
+	}
+
+	// The cause is reachable through wrapping
+	var err error = fmt.Errorf("handler: %w", tests[1].err)
+
+	if !errors.Is(err, ErrInvalidPrompt) || errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected only ErrInvalidPrompt to match, got %v", err)
+	}
+
+	var dbErr *PromptsDBError
+	if !errors.As(err, &dbErr) || dbErr.Op != "create" {
+		t.Errorf("Expected PromptsDBError of create, got %v", err)
+	}
+}
+
+func TestFsProviderErrors(t *testing.T) {
+	teamDir, personalDir := newTestDirectories(t)
+
+	provider, err := NewPromptsFsProviderWithDirectories([]FsDirectoryConfiguration{
+		{Path: teamDir, Precedence: 10},
+		{Path: personalDir, Writable: true},
+	}, "")
+	if err != nil {
+		t.Fatalf("Failed to create FsProvider: %v", err)
+	}
+
+	if _, err = provider.Read("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound when reading, got %v", err)
+	}
+	if err = provider.Delete("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound when deleting, got %v", err)
+	}
+	if err = provider.Update(Prompt{Name: "shared"}); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly when updating, got %v", err)
+	}
+	if err = provider.Delete("team_only"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly when deleting, got %v", err)
+	}
+	if err = provider.Create(Prompt{Name: "../escape"}); !errors.Is(err, ErrInvalidPrompt) {
+		t.Errorf("Expected ErrInvalidPrompt when creating, got %v", err)
+	}
+	for _, file := range []string{"../secret.txt", "/etc/passwd"} {
+		if err = provider.Create(Prompt{Name: "leaky", Messages: []Message{{File: file}}}); !errors.Is(err, ErrInvalidPrompt) {
+			t.Errorf("Expected ErrInvalidPrompt when creating a prompt sending %s, got %v", file, err)
+		}
+	}
+	if err = provider.Create(Prompt{Name: "ambiguous", Messages: []Message{{Content: "Hi", File: "style.txt"}}}); !errors.Is(err, ErrInvalidPrompt) {
+		t.Errorf("Expected ErrInvalidPrompt when creating a message with content and a file, got %v", err)
+	}
 
-	// Should return empty string
-	if err.Error() != "" {
-		t.Errorf("Expected empty error string, got '%s'", err.Error())
+	// Failures of the storage itself are not mistaken for the sentinel errors
+	if err = os.Chmod(personalDir, 0555); err != nil {
+		t.Fatalf("Failed to make directory read-only: %v", err)
+	}
+	defer os.Chmod(personalDir, 0755)
+
+	if os.Geteuid() != 0 {
+		err = provider.Create(Prompt{Name: "unwritable"})
+		if err == nil || errors.Is(err, ErrReadOnly) || errors.Is(err, ErrInvalidPrompt) {
+			t.Errorf("Expected a filesystem error, got %v", err)
+		}
 	}
 }
 
@@ -1036,20 +1782,19 @@ func TestSavePromptFunction(t *testing.T) {
 		Name:        "test-prompt",
 		Title:       "Test Prompt",
 		Description: "A test prompt",
-		Arguments:   []string{"arg1", "arg2"},
+		Arguments:   []Argument{{Name: "arg1"}, {Name: "arg2"}},
 		Content:     "Test content",
 		Tags:        []string{"test", "example"},
 	}
 
 	// Save the prompt
-	savedPath, err := savePrompt(prompt, tempDir)
-	if err != nil {
-		t.Fatalf("Failed to save prompt: %v", err)
+	savedPath := promptPath(tempDir, prompt.Id)
+	if savedPath != filepath.Join(tempDir, "test-prompt.md") {
+		t.Errorf("Expected path '%s', got '%s'", filepath.Join(tempDir, "test-prompt.md"), savedPath)
 	}
 
-	expectedPath := filepath.Join(tempDir, "test-prompt.md")
-	if savedPath != expectedPath {
-		t.Errorf("Expected path '%s', got '%s'", expectedPath, savedPath)
+	if err := savePrompt(prompt, savedPath); err != nil {
+		t.Fatalf("Failed to save prompt: %v", err)
 	}
 
 	// Verify file was created
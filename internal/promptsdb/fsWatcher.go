@@ -0,0 +1,292 @@
+package promptsdb
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/hkionline/prompter/internal/plog"
+)
+
+const (
+	WATCH_DEBOUNCE = 200 * time.Millisecond // time to wait for a burst of file events to settle before reloading
+)
+
+// Watch starts following the prompts directories and keeps the cache in sync
+// with prompt files that are added, changed or removed outside of the provider
+func (f *FsProvider) Watch() error {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.watcher != nil {
+		return nil
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range f.dirs {
+
+		if err = f.watchTree(watcher, dir.path); err != nil {
+			watcher.Close()
+			return err
+		}
+
+		f.logger.Write(plog.SERVER, "watching prompts directory "+dir.path+" for changes")
+	}
+
+	f.watcher = watcher
+
+	go f.watch(watcher)
+
+	return nil
+}
+
+// Close stops watching the prompts directories
+func (f *FsProvider) Close() error {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for path, timer := range f.pending {
+		timer.Stop()
+		delete(f.pending, path)
+	}
+
+	if f.watcher == nil {
+		return nil
+	}
+
+	err := f.watcher.Close()
+	f.watcher = nil
+
+	return err
+}
+
+// watch consumes the watcher events until the watcher is closed
+func (f *FsProvider) watch(watcher *fsnotify.Watcher) {
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+
+			if filepath.Ext(event.Name) == ".md" {
+				f.schedule(event.Name)
+				continue
+			}
+
+			f.namespaceChanged(watcher, event)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+
+			f.logger.Write(plog.SERVER, "prompts directory watcher error: "+err.Error())
+		}
+	}
+}
+
+// watchTree adds the directory and its subdirectories to the watcher,
+// fsnotify does not follow subdirectories by itself
+func (f *FsProvider) watchTree(watcher *fsnotify.Watcher, root string) error {
+
+	return filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+
+		if err != nil {
+			return err
+		}
+
+		if !entry.IsDir() {
+			return nil
+		}
+
+		if path != root && strings.HasPrefix(entry.Name(), ".") {
+			return filepath.SkipDir
+		}
+
+		return watcher.Add(path)
+	})
+}
+
+// namespaceChanged follows namespace folders that are added, removed or moved
+func (f *FsProvider) namespaceChanged(watcher *fsnotify.Watcher, event fsnotify.Event) {
+
+	if strings.HasPrefix(filepath.Base(event.Name), ".") {
+		return
+	}
+
+	if event.Has(fsnotify.Create) {
+
+		if info, err := os.Stat(event.Name); err != nil || !info.IsDir() {
+			return
+		}
+
+		if err := f.watchTree(watcher, event.Name); err != nil {
+			f.logger.Write(plog.SERVER, "failed to watch prompts folder "+event.Name+": "+err.Error())
+		}
+
+		// Files may have been written before the folder was watched or the folder was moved in
+		filepath.WalkDir(event.Name, func(path string, entry fs.DirEntry, err error) error {
+			if err == nil && !entry.IsDir() && filepath.Ext(path) == ".md" {
+				f.schedule(path)
+			}
+			return nil
+		})
+
+		return
+	}
+
+	if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+
+		// Prompt files of a folder moved away get no events of their own
+		f.mu.RLock()
+		paths := []string{}
+		for _, promptFile := range f.files {
+			if strings.HasPrefix(promptFile, event.Name+string(filepath.Separator)) {
+				paths = append(paths, promptFile)
+			}
+		}
+		f.mu.RUnlock()
+
+		for _, path := range paths {
+			f.schedule(path)
+		}
+	}
+}
+
+// schedule reloads the given prompt file once its events have settled,
+// so that editors writing a file in several steps cause only one reload
+func (f *FsProvider) schedule(path string) {
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if timer, ok := f.pending[path]; ok {
+		timer.Reset(WATCH_DEBOUNCE)
+		return
+	}
+
+	f.pending[path] = time.AfterFunc(WATCH_DEBOUNCE, func() {
+		f.reload(path)
+	})
+}
+
+// reload brings the cache in line with the current state of a prompt file
+func (f *FsProvider) reload(path string) {
+
+	prompt, err := loadPrompt(path, f.logger)
+
+	changes := []Change{}
+	defer f.notify(&changes)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.pending, path)
+
+	dir, promptFile, ok := f.directoryOf(path)
+
+	if !ok {
+		return
+	}
+
+	if err != nil {
+
+		if !errors.Is(err, os.ErrNotExist) {
+			// Keep serving the previous version until the file parses again
+			f.logger.Write(plog.SERVER, "failed to reload prompt file "+path+": "+err.Error())
+			return
+		}
+
+		if promptId, ok := dir.promptIdOf(promptFile); ok {
+			f.logger.Write(plog.SERVER, "prompt file "+path+" was removed")
+			delete(dir.cache, promptId)
+			delete(dir.files, promptId)
+
+			if change, changed := f.resolve(promptId); changed {
+				changes = append(changes, change)
+			}
+		}
+
+		return
+	}
+
+	prompt = namespaced(prompt, promptFile)
+	prompt.Source = dir.path
+
+	// The name in the frontmatter may have changed, drop the entry under the old name
+	if promptId, ok := dir.promptIdOf(promptFile); ok && promptId != prompt.Id {
+		delete(dir.cache, promptId)
+		delete(dir.files, promptId)
+
+		if change, changed := f.resolve(promptId); changed {
+			changes = append(changes, change)
+		}
+	}
+
+	// Writes made by the provider itself come back as events, the cached prompt has the
+	// revision of the file already
+	if cached, exists := dir.cache[prompt.Id]; exists && cached.Revision == prompt.Revision {
+		return
+	}
+
+	dir.cache[prompt.Id] = prompt
+	dir.files[prompt.Id] = promptFile
+
+	if change, changed := f.resolve(prompt.Id); changed {
+		changes = append(changes, change)
+	}
+}
+
+// directoryOf finds the prompts directory the given file is in and the slash separated
+// path of the file relative to it, callers must hold the lock
+func (f *FsProvider) directoryOf(path string) (*fsDirectory, string, bool) {
+
+	var found *fsDirectory
+	promptFile := ""
+
+	for _, dir := range f.dirs {
+
+		relative, err := filepath.Rel(dir.path, path)
+
+		if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+			continue
+		}
+
+		// Prefer the innermost directory when prompts directories are nested
+		if found == nil || len(dir.path) > len(found.path) {
+			found = dir
+			promptFile = filepath.ToSlash(relative)
+		}
+	}
+
+	return found, promptFile, found != nil
+}
+
+// promptIdOf finds the id of the prompt stored in the given file
+func (d *fsDirectory) promptIdOf(promptFile string) (string, bool) {
+
+	for promptId, file := range d.files {
+		if file == promptFile {
+			return promptId, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,305 @@
+package promptsdb
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// waitFor polls the condition until it holds or the timeout passes
+func waitFor(t *testing.T, condition func() bool) bool {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for time.Now().Before(deadline) {
+		if condition() {
+			return true
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	return false
+}
+
+func newWatchedProvider(t *testing.T) (*FsProvider, string) {
+	t.Helper()
+
+	tempDir := t.TempDir()
+
+	provider, err := NewPromptsFsProvider(tempDir, filepath.Join(t.TempDir(), "test.log"))
+	if err != nil {
+		t.Fatalf("Failed to create FsProvider: %v", err)
+	}
+
+	if err = provider.Watch(); err != nil {
+		t.Fatalf("Failed to watch prompts directory: %v", err)
+	}
+
+	t.Cleanup(func() { provider.Close() })
+
+	return provider, tempDir
+}
+
+func TestWatchAddsNewPromptFile(t *testing.T) {
+	provider, tempDir := newWatchedProvider(t)
+
+	promptContent := `---
+name: watched
+title: Watched Prompt
+---
+Watched content`
+
+	err := os.WriteFile(filepath.Join(tempDir, "watched.md"), []byte(promptContent), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create prompt file: %v", err)
+	}
+
+	found := waitFor(t, func() bool {
+		_, err := provider.Read("watched")
+		return err == nil
+	})
+
+	if !found {
+		t.Fatal("Expected watched prompt to be loaded into the cache")
+	}
+
+	prompt, _ := provider.Read("watched")
+	if prompt.Content != "Watched content" {
+		t.Errorf("Expected content 'Watched content', got '%s'", prompt.Content)
+	}
+}
+
+func TestWatchUpdatesChangedPromptFile(t *testing.T) {
+	provider, tempDir := newWatchedProvider(t)
+
+	err := provider.Create(Prompt{Name: "changing", Title: "Before", Content: "Before"})
+	if err != nil {
+		t.Fatalf("Failed to create prompt: %v", err)
+	}
+
+	promptContent := `---
+name: changing
+title: After
+---
+After`
+
+	err = os.WriteFile(filepath.Join(tempDir, "changing.md"), []byte(promptContent), 0644)
+	if err != nil {
+		t.Fatalf("Failed to change prompt file: %v", err)
+	}
+
+	updated := waitFor(t, func() bool {
+		prompt, err := provider.Read("changing")
+		return err == nil && prompt.Title == "After" && prompt.Content == "After"
+	})
+
+	if !updated {
+		t.Error("Expected changed prompt file to be reloaded")
+	}
+}
+
+func TestWatchIgnoresOwnWrites(t *testing.T) {
+	provider, _ := newWatchedProvider(t)
+
+	var mu sync.Mutex
+	changes := []Change{}
+
+	provider.Subscribe(func(change Change) {
+		mu.Lock()
+		defer mu.Unlock()
+		changes = append(changes, change)
+	})
+
+	// Neither the untrimmed content nor the missing arguments and tags are kept as such in the file
+	err := provider.Create(Prompt{Name: "own", Content: "  Own content\n\n"})
+	if err != nil {
+		t.Fatalf("Failed to create prompt: %v", err)
+	}
+
+	time.Sleep(5 * WATCH_DEBOUNCE)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(changes) != 1 || changes[0].Type != PROMPT_CREATED {
+		t.Errorf("Expected only the change creating the prompt, got %+v", changes)
+	}
+}
+
+func TestWatchRemovesDeletedPromptFile(t *testing.T) {
+	provider, tempDir := newWatchedProvider(t)
+
+	err := provider.Create(Prompt{Name: "removed", Content: "Content"})
+	if err != nil {
+		t.Fatalf("Failed to create prompt: %v", err)
+	}
+
+	err = os.Remove(filepath.Join(tempDir, "removed.md"))
+	if err != nil {
+		t.Fatalf("Failed to remove prompt file: %v", err)
+	}
+
+	removed := waitFor(t, func() bool {
+		_, err := provider.Read("removed")
+		return err != nil
+	})
+
+	if !removed {
+		t.Error("Expected removed prompt file to be dropped from the cache")
+	}
+
+	if _, ok := provider.files["removed"]; ok {
+		t.Error("Expected removed prompt file to be dropped from the files map")
+	}
+}
+
+func TestWatchKeepsPromptOnParseFailure(t *testing.T) {
+	provider, tempDir := newWatchedProvider(t)
+
+	err := provider.Create(Prompt{Name: "broken", Title: "Working", Content: "Content"})
+	if err != nil {
+		t.Fatalf("Failed to create prompt: %v", err)
+	}
+
+	err = os.WriteFile(filepath.Join(tempDir, "broken.md"), []byte("---\nname: broken\ntitle: [unclosed\n---\nContent"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to break prompt file: %v", err)
+	}
+
+	// Give the debounced reload time to run
+	time.Sleep(3 * WATCH_DEBOUNCE)
+
+	prompt, err := provider.Read("broken")
+	if err != nil {
+		t.Fatalf("Expected previous version of the prompt to be kept: %v", err)
+	}
+
+	if prompt.Title != "Working" {
+		t.Errorf("Expected title 'Working', got '%s'", prompt.Title)
+	}
+}
+
+func TestWatchDebouncesBursts(t *testing.T) {
+	provider, tempDir := newWatchedProvider(t)
+
+	path := filepath.Join(tempDir, "burst.md")
+
+	for i := range 5 {
+		content := []byte("---\nname: burst\ntitle: Burst\n---\nVersion " + string(rune('0'+i)))
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatalf("Failed to write prompt file: %v", err)
+		}
+	}
+
+	loaded := waitFor(t, func() bool {
+		prompt, err := provider.Read("burst")
+		return err == nil && prompt.Content == "Version 4"
+	})
+
+	if !loaded {
+		t.Error("Expected the last version of the prompt file to be loaded")
+	}
+}
+
+func TestCloseWithoutWatch(t *testing.T) {
+	provider, err := NewPromptsFsProvider(t.TempDir(), "")
+	if err != nil {
+		t.Fatalf("Failed to create FsProvider: %v", err)
+	}
+
+	if err = provider.Close(); err != nil {
+		t.Errorf("Expected Close to succeed without a watcher, got: %v", err)
+	}
+}
+
+func TestWatchMultipleDirectories(t *testing.T) {
+	teamDir := t.TempDir()
+	personalDir := t.TempDir()
+
+	provider, err := NewPromptsFsProviderWithDirectories([]FsDirectoryConfiguration{
+		{Path: teamDir, Precedence: 10},
+		{Path: personalDir, Writable: true},
+	}, filepath.Join(t.TempDir(), "test.log"))
+	if err != nil {
+		t.Fatalf("Failed to create FsProvider: %v", err)
+	}
+
+	if err = provider.Watch(); err != nil {
+		t.Fatalf("Failed to watch prompts directories: %v", err)
+	}
+	defer provider.Close()
+
+	if err = provider.Create(Prompt{Name: "shadowed", Content: "Personal content"}); err != nil {
+		t.Fatalf("Failed to create prompt: %v", err)
+	}
+
+	// A prompt appearing in the directory with higher precedence takes over
+	err = os.WriteFile(filepath.Join(teamDir, "shadowed.md"), []byte("---\nname: shadowed\n---\nTeam content"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create prompt file: %v", err)
+	}
+
+	replaced := waitFor(t, func() bool {
+		prompt, _ := provider.Read("shadowed")
+		return prompt.Source == teamDir
+	})
+
+	if !replaced {
+		t.Fatal("Expected prompt of the team directory to replace the personal one")
+	}
+
+	// Removing it reveals the personal prompt again
+	if err = os.Remove(filepath.Join(teamDir, "shadowed.md")); err != nil {
+		t.Fatalf("Failed to remove prompt file: %v", err)
+	}
+
+	restored := waitFor(t, func() bool {
+		prompt, _ := provider.Read("shadowed")
+		return prompt.Source == personalDir && prompt.Content == "Personal content"
+	})
+
+	if !restored {
+		t.Error("Expected personal prompt to be served after the team prompt was removed")
+	}
+}
+
+func TestWatchNamespaceFolders(t *testing.T) {
+	provider, tempDir := newWatchedProvider(t)
+
+	// Prompt files of a new folder are picked up under its namespace
+	reviewDir := filepath.Join(tempDir, "review")
+	if err := os.MkdirAll(filepath.Join(reviewDir, "deep"), 0755); err != nil {
+		t.Fatalf("Failed to create namespace folder: %v", err)
+	}
+
+	err := os.WriteFile(filepath.Join(reviewDir, "deep", "nested.md"), []byte("---\nname: nested\n---\nNested content"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to create prompt file: %v", err)
+	}
+
+	found := waitFor(t, func() bool {
+		_, err := provider.Read("review/deep/nested")
+		return err == nil
+	})
+
+	if !found {
+		t.Fatal("Expected prompt in the new namespace folder to be loaded")
+	}
+
+	// Moving the folder out of the prompts directory removes its prompts
+	if err = os.Rename(reviewDir, filepath.Join(t.TempDir(), "review")); err != nil {
+		t.Fatalf("Failed to move namespace folder: %v", err)
+	}
+
+	removed := waitFor(t, func() bool {
+		_, err := provider.Read("review/deep/nested")
+		return err != nil
+	})
+
+	if !removed {
+		t.Error("Expected prompts of the moved folder to be removed from the cache")
+	}
+}
@@ -0,0 +1,220 @@
+package promptsdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/hkionline/prompter/internal/plog"
+)
+
+// GitProvider stores the prompt files in a local git repository and commits
+// every change made through it. Reading and listing are served from the files
+// of the configured branch which is checked out when the provider is set up.
+type GitProvider struct {
+	mu     sync.Mutex  // serializes changes so that each one becomes its own commit
+	fs     *FsProvider // prompt files in the working tree of the repository
+	repo   string      // working tree of the repository
+	author []string    // git options setting the commit author, empty to use the git configuration
+	logger *plog.Plogger
+}
+
+type GitProviderConfiguration struct {
+	Repository  string `yaml:"repository" koanf:"repository"`     // local git repository to store the prompt files in, initialized when missing
+	Branch      string `yaml:"branch" koanf:"branch"`             // branch checked out on startup and committed to
+	AuthorName  string `yaml:"author_name" koanf:"author_name"`   // commit author name, defaults to the git configuration
+	AuthorEmail string `yaml:"author_email" koanf:"author_email"` // commit author email, defaults to the git configuration
+}
+
+func NewPromptsGitProvider(config GitProviderConfiguration, logfile string) (*GitProvider, error) {
+
+	p := plog.New(logfile)
+
+	p.Write(plog.SERVER, "setting up new prompts git provider")
+
+	g := &GitProvider{
+		repo:   config.Repository,
+		logger: p,
+	}
+
+	if config.AuthorName != "" {
+		g.author = append(g.author, "-c", "user.name="+config.AuthorName)
+	}
+
+	if config.AuthorEmail != "" {
+		g.author = append(g.author, "-c", "user.email="+config.AuthorEmail)
+	}
+
+	if err := g.checkout(config.Branch); err != nil {
+		return &GitProvider{}, err
+	}
+
+	fs, err := NewPromptsFsProvider(config.Repository, logfile)
+
+	if err != nil {
+		return &GitProvider{}, err
+	}
+
+	g.fs = fs
+
+	return g, nil
+}
+
+func (g *GitProvider) Create(prompt Prompt) error {
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.fs.Create(prompt); err != nil {
+		return err
+	}
+
+	promptFile, _ := g.fs.fileOf(prompt.Name)
+
+	g.commit(promptFile, fmt.Sprintf("Create prompt %s", prompt.Name))
+
+	return nil
+}
+
+func (g *GitProvider) Read(promptId string) (Prompt, error) {
+	return g.fs.Read(promptId)
+}
+
+func (g *GitProvider) Update(prompt Prompt) error {
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := g.fs.Update(prompt); err != nil {
+		return err
+	}
+
+	promptFile, _ := g.fs.fileOf(prompt.Name)
+
+	g.commit(promptFile, fmt.Sprintf("Update prompt %s", prompt.Name))
+
+	return nil
+}
+
+func (g *GitProvider) Delete(promptId string) error {
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	promptFile, ok := g.fs.fileOf(promptId)
+
+	if err := g.fs.Delete(promptId); err != nil {
+		return err
+	}
+
+	if ok {
+		g.commit(promptFile, fmt.Sprintf("Delete prompt %s", promptId))
+	}
+
+	return nil
+}
+
+func (g *GitProvider) List(query PromptQuery) ([]Prompt, int, error) {
+	return g.fs.List(query)
+}
+
+// Subscribe registers a listener to be called whenever the prompts change
+func (g *GitProvider) Subscribe(listener func(change Change)) {
+	g.fs.Subscribe(listener)
+}
+
+// Close releases the prompt files of the repository
+func (g *GitProvider) Close() error {
+	return g.fs.Close()
+}
+
+// checkout initializes the repository when needed and switches to the branch,
+// creating it from the current HEAD when it does not exist yet
+func (g *GitProvider) checkout(branch string) error {
+
+	if err := os.MkdirAll(g.repo, 0755); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(filepath.Join(g.repo, ".git")); errors.Is(err, os.ErrNotExist) {
+
+		g.logger.Write(plog.SERVER, "initializing prompts git repository "+g.repo)
+
+		if _, err = g.git("init"); err != nil {
+			return err
+		}
+	}
+
+	if branch == "" {
+		return nil
+	}
+
+	// A repository without commits only needs HEAD pointed at the branch
+	if _, err := g.git("rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+		_, err = g.git("symbolic-ref", "HEAD", "refs/heads/"+branch)
+		return err
+	}
+
+	if _, err := g.git("rev-parse", "--verify", "--quiet", "refs/heads/"+branch); err != nil {
+		_, err = g.git("checkout", "-b", branch)
+		return err
+	}
+
+	_, err := g.git("checkout", branch)
+
+	return err
+}
+
+// commit records the current state of the prompt file, only the given file
+// is committed so that other work in the repository is left alone. The change
+// is saved already when it is committed, so a failed commit is only logged and
+// the change stays in the working tree to be committed with the next change of
+// the prompt file.
+func (g *GitProvider) commit(promptFile string, message string) {
+
+	relative, err := filepath.Rel(g.repo, promptFile)
+
+	if err == nil {
+		_, err = g.git("add", "--all", "--", relative)
+	}
+
+	if err != nil {
+		g.logger.Write(plog.SERVER, "prompt saved but not committed: "+message, err.Error())
+		return
+	}
+
+	// Saving a prompt without changes leaves nothing to commit
+	if _, err = g.git("diff", "--cached", "--quiet", "--", relative); err == nil {
+		return
+	}
+
+	if _, err = g.git("commit", "--quiet", "-m", message, "--", relative); err != nil {
+		g.logger.Write(plog.SERVER, "prompt saved but not committed: "+message, err.Error())
+		return
+	}
+
+	g.logger.Write(plog.SERVER, "committed to prompts repository: "+message)
+}
+
+// git runs a git command in the repository and returns its output
+func (g *GitProvider) git(args ...string) (string, error) {
+
+	cmd := exec.Command("git", slices.Concat(g.author, args)...)
+	cmd.Dir = g.repo
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
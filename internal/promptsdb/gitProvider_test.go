@@ -0,0 +1,221 @@
+package promptsdb
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestGitConfiguration(t *testing.T) GitProviderConfiguration {
+	t.Helper()
+
+	return GitProviderConfiguration{
+		Repository:  filepath.Join(t.TempDir(), "prompts"),
+		Branch:      "main",
+		AuthorName:  "Prompter Test",
+		AuthorEmail: "prompter@example.com",
+	}
+}
+
+// gitOutput runs a git command in the repository for inspecting the results
+func gitOutput(t *testing.T, repo string, args ...string) string {
+	t.Helper()
+
+	cmd := exec.Command("git", append([]string{"-c", "user.name=Test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = repo
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v failed: %v: %s", args, err, output)
+	}
+
+	return strings.TrimSpace(string(output))
+}
+
+func TestGitProviderCommitsEveryChange(t *testing.T) {
+	config := newTestGitConfiguration(t)
+
+	provider, err := NewPromptsGitProvider(config, filepath.Join(t.TempDir(), "test.log"))
+	if err != nil {
+		t.Fatalf("Failed to create GitProvider: %v", err)
+	}
+	defer provider.Close()
+
+	if err = provider.Create(Prompt{Name: "versioned", Title: "First", Content: "First version"}); err != nil {
+		t.Fatalf("Failed to create prompt: %v", err)
+	}
+
+	if err = provider.Update(Prompt{Name: "versioned", Title: "Second", Content: "Second version"}); err != nil {
+		t.Fatalf("Failed to update prompt: %v", err)
+	}
+
+	// Saving the same prompt again does not create an empty commit
+	if err = provider.Update(Prompt{Name: "versioned", Title: "Second", Content: "Second version"}); err != nil {
+		t.Fatalf("Failed to update prompt without changes: %v", err)
+	}
+
+	if err = provider.Delete("versioned"); err != nil {
+		t.Fatalf("Failed to delete prompt: %v", err)
+	}
+
+	log := gitOutput(t, config.Repository, "log", "--format=%s|%an", "main")
+	expected := "Delete prompt versioned|Prompter Test\nUpdate prompt versioned|Prompter Test\nCreate prompt versioned|Prompter Test"
+
+	if log != expected {
+		t.Errorf("Expected commit history:\n%s\ngot:\n%s", expected, log)
+	}
+
+	// The previous versions can be restored from the history
+	content := gitOutput(t, config.Repository, "show", "HEAD~2:versioned.md")
+	if !strings.Contains(content, "First version") {
+		t.Errorf("Expected first version in history, got:\n%s", content)
+	}
+}
+
+func TestGitProviderKeepsChangeWhenCommitFails(t *testing.T) {
+	config := newTestGitConfiguration(t)
+
+	provider, err := NewPromptsGitProvider(config, filepath.Join(t.TempDir(), "test.log"))
+	if err != nil {
+		t.Fatalf("Failed to create GitProvider: %v", err)
+	}
+	defer provider.Close()
+
+	// A hook refusing every commit
+	hook := filepath.Join(config.Repository, ".git", "hooks", "pre-commit")
+	if err = os.WriteFile(hook, []byte("#!/bin/sh\nexit 1\n"), 0755); err != nil {
+		t.Fatalf("Failed to write hook: %v", err)
+	}
+
+	if err = provider.Create(Prompt{Name: "uncommitted", Content: "First version"}); err != nil {
+		t.Fatalf("Expected the prompt to be saved although the commit failed, got: %v", err)
+	}
+
+	if _, err = provider.Read("uncommitted"); err != nil {
+		t.Fatalf("Failed to read saved prompt: %v", err)
+	}
+
+	// The change left in the working tree is committed with the next change of the prompt
+	if err = os.Remove(hook); err != nil {
+		t.Fatalf("Failed to remove hook: %v", err)
+	}
+
+	if err = provider.Update(Prompt{Name: "uncommitted", Content: "Second version"}); err != nil {
+		t.Fatalf("Failed to update prompt: %v", err)
+	}
+
+	log := gitOutput(t, config.Repository, "log", "--format=%s", "main")
+	if log != "Update prompt uncommitted" {
+		t.Errorf("Expected only the update to be committed, got:\n%s", log)
+	}
+
+	content := gitOutput(t, config.Repository, "show", "HEAD:uncommitted.md")
+	if !strings.Contains(content, "Second version") {
+		t.Errorf("Expected second version to be committed, got:\n%s", content)
+	}
+}
+
+func TestGitProviderLeavesOtherChangesAlone(t *testing.T) {
+	config := newTestGitConfiguration(t)
+
+	provider, err := NewPromptsGitProvider(config, filepath.Join(t.TempDir(), "test.log"))
+	if err != nil {
+		t.Fatalf("Failed to create GitProvider: %v", err)
+	}
+	defer provider.Close()
+
+	// Work in progress staged by someone else
+	err = os.WriteFile(filepath.Join(config.Repository, "notes.txt"), []byte("work in progress"), 0644)
+	if err != nil {
+		t.Fatalf("Failed to write notes: %v", err)
+	}
+	gitOutput(t, config.Repository, "add", "notes.txt")
+
+	if err = provider.Create(Prompt{Name: "isolated", Content: "Content"}); err != nil {
+		t.Fatalf("Failed to create prompt: %v", err)
+	}
+
+	files := gitOutput(t, config.Repository, "show", "--name-only", "--format=", "HEAD")
+	if files != "isolated.md" {
+		t.Errorf("Expected only isolated.md to be committed, got: %s", files)
+	}
+
+	status := gitOutput(t, config.Repository, "status", "--porcelain")
+	if status != "A  notes.txt" {
+		t.Errorf("Expected notes.txt to stay staged, got: %s", status)
+	}
+}
+
+func TestGitProviderReadsConfiguredBranch(t *testing.T) {
+	config := newTestGitConfiguration(t)
+
+	provider, err := NewPromptsGitProvider(config, filepath.Join(t.TempDir(), "test.log"))
+	if err != nil {
+		t.Fatalf("Failed to create GitProvider: %v", err)
+	}
+
+	if err = provider.Create(Prompt{Name: "on-main", Content: "Main content"}); err != nil {
+		t.Fatalf("Failed to create prompt: %v", err)
+	}
+	provider.Close()
+
+	// A provider on another branch starts from the current HEAD and keeps its own history
+	config.Branch = "team"
+
+	teamProvider, err := NewPromptsGitProvider(config, filepath.Join(t.TempDir(), "test.log"))
+	if err != nil {
+		t.Fatalf("Failed to create GitProvider on a new branch: %v", err)
+	}
+
+	if _, err = teamProvider.Read("on-main"); err != nil {
+		t.Errorf("Expected prompt from the branch point to be available: %v", err)
+	}
+
+	if err = teamProvider.Create(Prompt{Name: "on-team", Content: "Team content"}); err != nil {
+		t.Fatalf("Failed to create prompt: %v", err)
+	}
+	teamProvider.Close()
+
+	if branch := gitOutput(t, config.Repository, "rev-parse", "--abbrev-ref", "HEAD"); branch != "team" {
+		t.Errorf("Expected team branch to be checked out, got %s", branch)
+	}
+
+	// Switching back to main does not show the prompt committed to team
+	config.Branch = "main"
+
+	mainProvider, err := NewPromptsGitProvider(config, filepath.Join(t.TempDir(), "test.log"))
+	if err != nil {
+		t.Fatalf("Failed to create GitProvider on main: %v", err)
+	}
+	defer mainProvider.Close()
+
+	if _, err = mainProvider.Read("on-team"); err == nil {
+		t.Error("Expected prompt of the team branch to be missing from main")
+	}
+
+	if _, err = mainProvider.Read("on-main"); err != nil {
+		t.Errorf("Expected prompt of main to be available: %v", err)
+	}
+}
+
+func TestNewWithGitProvider(t *testing.T) {
+	config := ProviderConfiguration{
+		Provider: GIT_PROVIDER,
+		Git:      newTestGitConfiguration(t),
+	}
+
+	provider, err := New(GIT_PROVIDER, config, filepath.Join(t.TempDir(), "test.log"))
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	if _, ok := provider.(*GitProvider); !ok {
+		t.Fatalf("Expected *GitProvider, got %T", provider)
+	}
+
+	if _, err = os.Stat(filepath.Join(config.Git.Repository, ".git")); err != nil {
+		t.Errorf("Expected repository to be initialized: %v", err)
+	}
+}
@@ -1,15 +1,47 @@
 package promptsdb
 
+import "fmt"
+
 const (
-	FILE_SYSTEM_PROVIDER = "fsProvider"
+	FILE_SYSTEM_PROVIDER = "filesystem"
+	SQLITE_PROVIDER      = "sqlite"
+	GIT_PROVIDER         = "git"
 )
 
 func New(dbProvider string, config ProviderConfiguration, logfile string) (Provider, error) {
 
 	switch dbProvider {
-	case FILE_SYSTEM_PROVIDER:
-		return NewPromptsFsProvider(config.Filesystem.Directory, logfile)
+	case FILE_SYSTEM_PROVIDER, "":
+		return newFsProvider(config.Filesystem, logfile)
+	case SQLITE_PROVIDER:
+		return NewPromptsSqliteProvider(config.Sqlite.Path, logfile)
+	case GIT_PROVIDER:
+		return NewPromptsGitProvider(config.Git, logfile)
 	default:
-		return NewPromptsFsProvider(config.Filesystem.Directory, logfile)
+		// A misspelled provider would otherwise serve prompts from an unexpected place
+		return nil, fmt.Errorf("unknown storage provider %q, use %s, %s or %s", dbProvider, FILE_SYSTEM_PROVIDER, SQLITE_PROVIDER, GIT_PROVIDER)
+	}
+}
+
+// newFsProvider sets up the filesystem provider and starts watching it when configured to
+func newFsProvider(config FsProviderConfiguration, logfile string) (*FsProvider, error) {
+
+	directories := config.Directories
+
+	// A single prompts directory is both read and written
+	if len(directories) == 0 {
+		directories = []FsDirectoryConfiguration{{Path: config.Directory, Writable: true}}
 	}
+
+	provider, err := NewPromptsFsProviderWithDirectories(directories, logfile)
+
+	if err != nil {
+		return provider, err
+	}
+
+	if config.Watch {
+		err = provider.Watch()
+	}
+
+	return provider, err
 }
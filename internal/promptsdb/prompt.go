@@ -1,26 +1,287 @@
 package promptsdb
 
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ROLE_USER      = "user"      // role of the messages sent by the user of the prompt
+	ROLE_ASSISTANT = "assistant" // role of the messages answered by the model, e.g. few-shot examples
+)
+
 type Prompt struct {
-	Id          string   `json:"-" yaml:"id"`                              // Unique computer readable datastorage engine identifier
-	Name        string   `json:"name,omitempty" yaml:"name"`               // Unique programmatic or logical name used to invoke the prompt
-	Title       string   `json:"title,omitempty" yaml:"title"`             // Human readable title of the prompt
-	Description string   `json:"description,omitempty" yaml:"description"` // Human readable longer explanation what the prompt is
-	Arguments   []string `json:"arguments,omitzero" yaml:"arguments"`      // Arguments used in invoking the prompt
-	Content     string   `json:"content" yaml:"-"`                         // The contents of the actual prompt
-	Tags        []string `json:"-" yaml:"tags"`                            // Tags for the prompt, can be used for example in completion suggestions
+	Id          string         `json:"-" yaml:"id"`                                  // Unique computer readable datastorage engine identifier
+	Name        string         `json:"name,omitempty" yaml:"name"`                   // Unique programmatic or logical name used to invoke the prompt
+	Title       string         `json:"title,omitempty" yaml:"title"`                 // Human readable title of the prompt
+	Description string         `json:"description,omitempty" yaml:"description"`     // Human readable longer explanation what the prompt is
+	Arguments   []Argument     `json:"arguments,omitzero" yaml:"arguments"`          // Arguments used in invoking the prompt
+	Messages    []Message      `json:"messages,omitempty" yaml:"messages,omitempty"` // Conversation turns sent before the contents of the prompt
+	Extends     string         `json:"extends,omitempty" yaml:"extends,omitempty"`   // Name of the base prompt whose blocks the contents override
+	Content     string         `json:"content" yaml:"-"`                             // The contents of the actual prompt
+	Tags        []string       `json:"-" yaml:"tags"`                                // Tags for the prompt, can be used for example in completion suggestions
+	Partial     bool           `json:"partial,omitempty" yaml:"partial,omitempty"`   // Whether the prompt is only included in other prompts and not listed to clients
+	Source      string         `json:"-" yaml:"-"`                                   // Where the provider loaded the prompt from, e.g. the prompts directory
+	Revision    string         `json:"revision,omitempty" yaml:"-"`                  // Version of the stored prompt set by the provider, Update rejects stale revisions
+	Extra       map[string]any `json:"extra,omitempty" yaml:",inline"`               // Metadata the prompt does not model, kept as is when the prompt is saved
+}
+
+// Argument describes a value the prompt can be invoked with
+type Argument struct {
+	Name        string         `json:"name" yaml:"name"`                                   // Name of the argument as used in the prompt template
+	Description string         `json:"description,omitempty" yaml:"description,omitempty"` // Human readable explanation of the argument
+	Required    bool           `json:"required,omitempty" yaml:"required,omitempty"`       // Whether the prompt can not be invoked without the argument
+	Values      []string       `json:"values,omitempty" yaml:"values,omitempty"`           // The only values the argument accepts, offered as completions
+	Examples    []string       `json:"examples,omitempty" yaml:"examples,omitempty"`       // Example values offered as completions
+	Sensitive   bool           `json:"sensitive,omitempty" yaml:"sensitive,omitempty"`     // Whether the values, such as tokens, are kept out of the completion history
+	Type        string         `json:"type,omitempty" yaml:"type,omitempty"`               // Type the argument is coerced to before rendering, text when empty
+	Default     any            `json:"default,omitempty" yaml:"default,omitempty"`         // Value used when the argument is not given
+	Minimum     *float64       `json:"minimum,omitempty" yaml:"minimum,omitempty"`         // Smallest value of a number argument
+	Maximum     *float64       `json:"maximum,omitempty" yaml:"maximum,omitempty"`         // Largest value of a number argument
+	MinLength   *int           `json:"min_length,omitempty" yaml:"min_length,omitempty"`   // Fewest characters of a text argument
+	MaxLength   *int           `json:"max_length,omitempty" yaml:"max_length,omitempty"`   // Most characters of a text argument
+	Pattern     string         `json:"pattern,omitempty" yaml:"pattern,omitempty"`         // Regular expression a text argument must match
+	Items       string         `json:"items,omitempty" yaml:"items,omitempty"`             // Type of the items of a list argument, text when empty
+	MinItems    *int           `json:"min_items,omitempty" yaml:"min_items,omitempty"`     // Fewest items of a list argument
+	MaxItems    *int           `json:"max_items,omitempty" yaml:"max_items,omitempty"`     // Most items of a list argument
+	Schema      map[string]any `json:"schema,omitempty" yaml:"schema,omitempty"`           // JSON Schema of an object argument
+}
+
+// Message is a single conversation turn of a prompt, the turn is either text or a local file
+type Message struct {
+	Role    string `json:"role" yaml:"role"`                           // Who the turn is from, user or assistant
+	Content string `json:"content,omitempty" yaml:"content,omitempty"` // Text of the turn, templated like the prompt contents
+	File    string `json:"file,omitempty" yaml:"file,omitempty"`       // Path of a file sent as the turn, relative to the prompts directory
+}
+
+// UnmarshalYAML accepts both a plain argument name and a mapping with the argument details
+func (a *Argument) UnmarshalYAML(node *yaml.Node) error {
+
+	if node.Kind == yaml.ScalarNode {
+		a.Name = node.Value
+		return nil
+	}
+
+	type argument Argument
+
+	return node.Decode((*argument)(a))
+}
+
+// MarshalYAML writes arguments without details as plain names
+func (a Argument) MarshalYAML() (any, error) {
+
+	if reflect.DeepEqual(a, Argument{Name: a.Name}) {
+		return a.Name, nil
+	}
+
+	type argument Argument
+
+	return argument(a), nil
+}
+
+// RequiredArguments returns the names of the arguments the prompt can not be invoked without
+func (p Prompt) RequiredArguments() []string {
+
+	required := []string{}
+
+	for _, argument := range p.Arguments {
+		if argument.Required {
+			required = append(required, argument.Name)
+		}
+	}
+
+	return required
+}
+
+// Conversation returns the messages of the prompt in the order they are sent to the client,
+// the contents of the prompt follow the frontmatter messages as the last user message also
+// when they only extend a base prompt
+func (p Prompt) Conversation() []Message {
+
+	messages := []Message{}
+
+	for _, message := range p.Messages {
+		if message.Role == "" {
+			message.Role = ROLE_USER
+		}
+		messages = append(messages, message)
+	}
+
+	if p.Content != "" || p.Extends != "" || len(messages) == 0 {
+		messages = append(messages, Message{Role: ROLE_USER, Content: p.Content})
+	}
+
+	return messages
+}
+
+// Markdown returns the prompt in the prompt file format, the YAML frontmatter followed by the contents
+func (p Prompt) Markdown() ([]byte, error) {
+
+	frontmatter, err := yaml.Marshal(p)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.Concat([]byte(FRONTMATTER_DELIMITER+"\n"), frontmatter, []byte(FRONTMATTER_DELIMITER+"\n"), []byte(p.Content)), nil
+}
+
+// Argument returns the argument with the given name
+func (p Prompt) Argument(name string) (Argument, bool) {
+
+	for _, argument := range p.Arguments {
+		if argument.Name == name {
+			return argument, true
+		}
+	}
+
+	return Argument{}, false
 }
 
+// Validate checks that the prompt can be stored, slashes in the name separate
+// the namespaces from the plain name of the prompt
+func (p Prompt) Validate() error {
+
+	if p.Name == "" {
+		return fmt.Errorf("%w: the name is empty", ErrInvalidPrompt)
+	}
+
+	if strings.Contains(p.Name, `\`) {
+		return fmt.Errorf("%w: backslashes are not allowed in the name", ErrInvalidPrompt)
+	}
+
+	for _, segment := range strings.Split(p.Name, "/") {
+		// Hidden folders are skipped when loading prompt files
+		if segment == "" || strings.HasPrefix(segment, ".") {
+			return fmt.Errorf("%w: namespaces and names can not be empty or start with a dot", ErrInvalidPrompt)
+		}
+	}
+
+	if p.Extends == p.Name {
+		return fmt.Errorf("%w: the prompt can not extend itself", ErrInvalidPrompt)
+	}
+
+	if err := p.validateArguments(); err != nil {
+		return err
+	}
+
+	return p.validateMessages()
+}
+
+// validateMessages checks that the frontmatter messages only use roles MCP clients understand
+// and that files are referenced inside the prompts directory
+func (p Prompt) validateMessages() error {
+
+	for i, message := range p.Messages {
+		if message.Role != "" && message.Role != ROLE_USER && message.Role != ROLE_ASSISTANT {
+			return fmt.Errorf("%w: message %d has unknown role %q", ErrInvalidPrompt, i+1, message.Role)
+		}
+
+		if message.File == "" {
+			continue
+		}
+
+		if message.Content != "" {
+			return fmt.Errorf("%w: message %d has both content and a file", ErrInvalidPrompt, i+1)
+		}
+
+		if !filepath.IsLocal(filepath.FromSlash(message.File)) {
+			return fmt.Errorf("%w: message %d file %s is not inside the prompts directory", ErrInvalidPrompt, i+1, message.File)
+		}
+	}
+
+	return nil
+}
+
+// Namespace returns the folder part of a namespaced prompt name, e.g. review for
+// review/security_audit, and an empty string for prompts without a namespace
+func (p Prompt) Namespace() string {
+
+	if i := strings.LastIndex(p.Name, "/"); i >= 0 {
+		return p.Name[:i]
+	}
+
+	return ""
+}
+
+// PromptQuery describes which prompts a provider should return from List.
+// Name, tag and namespace filters are applied first, the matching prompts are then sorted by name
+// and finally the IndexFrom (inclusive) - IndexTo (exclusive) window is applied.
+// A zero IndexTo means there is no upper bound. Setting All ignores every other field.
 type PromptQuery struct {
 	All            bool
 	NameStartsWith string
 	NameContains   string
+	Tag            string // only prompts tagged with the tag
+	Namespace      string // only prompts in the namespace or the namespaces nested in it
+	NoPartials     bool   // leave out the prompts marked as partials
 	IndexFrom      int
 	IndexTo        int
 }
 
-type PromptsDBError struct{}
+// Matches reports whether the prompt passes the name, tag, namespace and partial filters of the query
+func (q PromptQuery) Matches(prompt Prompt) bool {
 
-func (p *PromptsDBError) Error() string {
+	if q.All {
+		return true
+	}
 
-	return ""
+	if q.NameStartsWith != "" && !strings.HasPrefix(prompt.Name, q.NameStartsWith) {
+		return false
+	}
+
+	if q.NameContains != "" && !strings.Contains(prompt.Name, q.NameContains) {
+		return false
+	}
+
+	if q.Tag != "" && !slices.Contains(prompt.Tags, q.Tag) {
+		return false
+	}
+
+	if namespace := strings.Trim(q.Namespace, "/"); namespace != "" && !strings.HasPrefix(prompt.Name, namespace+"/") {
+		return false
+	}
+
+	if q.NoPartials && prompt.Partial {
+		return false
+	}
+
+	return true
+}
+
+// Apply filters and sorts the given prompts by name and returns the requested
+// window of them together with the total number of prompts matching the query
+func (q PromptQuery) Apply(prompts []Prompt) ([]Prompt, int) {
+
+	matches := []Prompt{}
+
+	for _, prompt := range prompts {
+		if q.Matches(prompt) {
+			matches = append(matches, prompt)
+		}
+	}
+
+	slices.SortFunc(matches, func(a, b Prompt) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	total := len(matches)
+
+	if q.All {
+		return matches, total
+	}
+
+	from := min(max(q.IndexFrom, 0), total)
+	to := total
+
+	if q.IndexTo > 0 {
+		to = min(max(q.IndexTo, from), total)
+	}
+
+	return matches[from:to], total
 }
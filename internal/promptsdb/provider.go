@@ -5,10 +5,31 @@ type Provider interface {
 	Read(promptId string) (Prompt, error)
 	Update(prompt Prompt) error
 	Delete(promptId string) error
-	List(query PromptQuery) ([]Prompt, error)
+	List(query PromptQuery) ([]Prompt, int, error) // returns the requested page of prompts and the total number of matches
+}
+
+const (
+	PROMPT_CREATED = "created" // change type for a prompt added to the provider
+	PROMPT_UPDATED = "updated" // change type for a prompt modified in the provider
+	PROMPT_DELETED = "deleted" // change type for a prompt removed from the provider
+)
+
+// Change describes a single modification to the prompts held by a provider
+type Change struct {
+	Type     string // one of PROMPT_CREATED, PROMPT_UPDATED or PROMPT_DELETED
+	PromptId string // id of the changed prompt
+}
+
+// Notifier is implemented by providers which can report changes to their prompts,
+// including the ones made outside of the provider such as edits to prompt files.
+// Listeners are called synchronously after the change has been applied.
+type Notifier interface {
+	Subscribe(listener func(change Change))
 }
 
 type ProviderConfiguration struct {
-	Provider   string                  `yaml:"provider"`
-	Filesystem FsProviderConfiguration `yaml:"filesystem"`
+	Provider   string                      `yaml:"provider" koanf:"provider"`
+	Filesystem FsProviderConfiguration     `yaml:"filesystem" koanf:"filesystem"`
+	Sqlite     SqliteProviderConfiguration `yaml:"sqlite" koanf:"sqlite"`
+	Git        GitProviderConfiguration    `yaml:"git" koanf:"git"`
 }
@@ -0,0 +1,527 @@
+package promptsdb
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/hkionline/prompter/internal/plog"
+	_ "modernc.org/sqlite"
+)
+
+// migrations bring the database schema up to date, the schema version is
+// the number of migrations applied and it is kept in PRAGMA user_version.
+// Never edit an existing migration, append a new one instead.
+var migrations = []string{
+	`CREATE TABLE prompts (
+		id          TEXT PRIMARY KEY,
+		name        TEXT NOT NULL UNIQUE,
+		title       TEXT NOT NULL DEFAULT '',
+		description TEXT NOT NULL DEFAULT '',
+		arguments   TEXT NOT NULL DEFAULT '[]',
+		content     TEXT NOT NULL DEFAULT ''
+	);
+	CREATE TABLE prompt_tags (
+		prompt_id TEXT NOT NULL REFERENCES prompts(id) ON DELETE CASCADE,
+		position  INTEGER NOT NULL,
+		tag       TEXT NOT NULL,
+		PRIMARY KEY (prompt_id, position)
+	);
+	CREATE INDEX prompt_tags_tag ON prompt_tags(tag, prompt_id);`,
+	`ALTER TABLE prompts ADD COLUMN revision INTEGER NOT NULL DEFAULT 1;`,
+	`ALTER TABLE prompts ADD COLUMN extra TEXT NOT NULL DEFAULT '{}';`,
+	`ALTER TABLE prompts ADD COLUMN messages TEXT NOT NULL DEFAULT '[]';`,
+	`ALTER TABLE prompts ADD COLUMN partial INTEGER NOT NULL DEFAULT 0;`,
+	`ALTER TABLE prompts ADD COLUMN extends TEXT NOT NULL DEFAULT '';`,
+}
+
+type SqliteProvider struct {
+	mu        sync.RWMutex
+	db        *sql.DB
+	logger    *plog.Plogger
+	listeners []func(change Change) // listeners notified of changes to the prompts
+}
+
+type SqliteProviderConfiguration struct {
+	Path string `yaml:"path" koanf:"path"` // path of the database file, created when missing
+}
+
+func NewPromptsSqliteProvider(dbPath string, logfile string) (*SqliteProvider, error) {
+
+	p := plog.New(logfile)
+
+	p.Write(plog.SERVER, "setting up new prompts sqlite provider")
+
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return &SqliteProvider{}, err
+	}
+
+	// WAL and a busy timeout let several prompter instances share the database,
+	// immediate transactions take the write lock up front instead of failing midway.
+	// The path is escaped as SQLite reads the DSN as a URI, e.g. a ? would start the query.
+	path := (&url.URL{Path: filepath.ToSlash(dbPath)}).EscapedPath()
+	dsn := fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_pragma=foreign_keys(1)&_txlock=immediate", path)
+
+	db, err := sql.Open("sqlite", dsn)
+
+	if err != nil {
+		return &SqliteProvider{}, err
+	}
+
+	if err = migrate(db, p); err != nil {
+		db.Close()
+		return &SqliteProvider{}, err
+	}
+
+	return &SqliteProvider{
+		db:     db,
+		logger: p,
+	}, nil
+}
+
+func (s *SqliteProvider) Create(prompt Prompt) error {
+
+	prompt.Id = prompt.Name
+
+	if err := prompt.Validate(); err != nil {
+		return newError("create", prompt.Id, err)
+	}
+
+	err := s.transaction(func(tx *sql.Tx) error {
+
+		columns, err := marshalColumns(prompt)
+
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(
+			`INSERT INTO prompts (id, name, title, description, arguments, messages, content, extra, partial, extends) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			prompt.Id, prompt.Name, prompt.Title, prompt.Description, columns.arguments, columns.messages, prompt.Content, columns.extra, prompt.Partial, prompt.Extends,
+		)
+
+		if err != nil {
+			if exists, _ := promptExists(tx, prompt.Id); exists {
+				return ErrAlreadyExists
+			}
+			return err
+		}
+
+		return saveTags(tx, prompt)
+	})
+
+	if err != nil {
+		return newError("create", prompt.Id, err)
+	}
+
+	s.notify(Change{Type: PROMPT_CREATED, PromptId: prompt.Id})
+
+	return nil
+}
+
+func (s *SqliteProvider) Read(promptId string) (Prompt, error) {
+
+	prompts, err := s.query(`WHERE p.id = ?`, promptId)
+
+	if err != nil {
+		return Prompt{}, newError("read", promptId, err)
+	}
+
+	if len(prompts) == 0 {
+		return Prompt{}, newError("read", promptId, ErrNotFound)
+	}
+
+	return prompts[0], nil
+}
+
+func (s *SqliteProvider) Update(prompt Prompt) error {
+
+	prompt.Id = prompt.Name
+
+	if err := prompt.Validate(); err != nil {
+		return newError("update", prompt.Id, err)
+	}
+
+	err := s.transaction(func(tx *sql.Tx) error {
+
+		columns, err := marshalColumns(prompt)
+
+		if err != nil {
+			return err
+		}
+
+		// An empty revision updates whatever revision is stored
+		result, err := tx.Exec(
+			`UPDATE prompts SET title = ?, description = ?, arguments = ?, messages = ?, content = ?, extra = ?, partial = ?, extends = ?, revision = revision + 1
+			WHERE id = ? AND (? = '' OR CAST(revision AS TEXT) = ?)`,
+			prompt.Title, prompt.Description, columns.arguments, columns.messages, prompt.Content, columns.extra, prompt.Partial, prompt.Extends, prompt.Id, prompt.Revision, prompt.Revision,
+		)
+
+		if err != nil {
+			return err
+		}
+
+		if updated, err := result.RowsAffected(); err != nil {
+			return err
+		} else if updated == 0 {
+			return staleOrMissing(tx, prompt)
+		}
+
+		return saveTags(tx, prompt)
+	})
+
+	if err != nil {
+		return newError("update", prompt.Id, err)
+	}
+
+	s.notify(Change{Type: PROMPT_UPDATED, PromptId: prompt.Id})
+
+	return nil
+}
+
+func (s *SqliteProvider) Delete(promptId string) error {
+
+	err := s.transaction(func(tx *sql.Tx) error {
+
+		// Tags are removed by the foreign key cascade
+		result, err := tx.Exec(`DELETE FROM prompts WHERE id = ?`, promptId)
+
+		if err != nil {
+			return err
+		}
+
+		if deleted, err := result.RowsAffected(); err != nil {
+			return err
+		} else if deleted == 0 {
+			return ErrNotFound
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return newError("delete", promptId, err)
+	}
+
+	s.notify(Change{Type: PROMPT_DELETED, PromptId: promptId})
+
+	return nil
+}
+
+func (s *SqliteProvider) List(query PromptQuery) ([]Prompt, int, error) {
+
+	where := []string{}
+	args := []any{}
+
+	if !query.All {
+
+		// GLOB is case sensitive like the other providers and can use the name index
+		if query.NameStartsWith != "" {
+			where = append(where, `p.name GLOB ?`)
+			args = append(args, escapeGlob(query.NameStartsWith)+"*")
+		}
+
+		if query.NameContains != "" {
+			where = append(where, `instr(p.name, ?) > 0`)
+			args = append(args, query.NameContains)
+		}
+
+		if query.Tag != "" {
+			where = append(where, `p.id IN (SELECT prompt_id FROM prompt_tags WHERE tag = ?)`)
+			args = append(args, query.Tag)
+		}
+
+		if namespace := strings.Trim(query.Namespace, "/"); namespace != "" {
+			where = append(where, `p.name GLOB ?`)
+			args = append(args, escapeGlob(namespace)+"/*")
+		}
+
+		if query.NoPartials {
+			where = append(where, `p.partial = 0`)
+		}
+	}
+
+	filter := ""
+
+	if len(where) > 0 {
+		filter = "WHERE " + strings.Join(where, " AND ")
+	}
+
+	var total int
+
+	if err := s.db.QueryRow(`SELECT count(*) FROM prompts p `+filter, args...).Scan(&total); err != nil {
+		return nil, 0, newError("list", "", err)
+	}
+
+	window := ""
+
+	if !query.All {
+
+		from := max(query.IndexFrom, 0)
+		limit := -1
+
+		if query.IndexTo > 0 {
+			limit = max(query.IndexTo-from, 0)
+		}
+
+		window = fmt.Sprintf("LIMIT %d OFFSET %d", limit, from)
+	}
+
+	prompts, err := s.query(filter+" ORDER BY p.name "+window, args...)
+
+	if err != nil {
+		return nil, 0, newError("list", "", err)
+	}
+
+	return prompts, total, nil
+}
+
+// Close closes the database
+func (s *SqliteProvider) Close() error {
+	return s.db.Close()
+}
+
+// Subscribe registers a listener to be called whenever the prompts change through this provider
+func (s *SqliteProvider) Subscribe(listener func(change Change)) {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.listeners = append(s.listeners, listener)
+}
+
+func (s *SqliteProvider) notify(change Change) {
+
+	s.mu.RLock()
+	listeners := slices.Clone(s.listeners)
+	s.mu.RUnlock()
+
+	for _, listener := range listeners {
+		listener(change)
+	}
+}
+
+// transaction runs the function in a transaction which is committed only when the function succeeds
+func (s *SqliteProvider) transaction(fn func(tx *sql.Tx) error) error {
+
+	tx, err := s.db.Begin()
+
+	if err != nil {
+		return err
+	}
+
+	if err = fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// query selects the prompts matching the given clause together with their tags
+func (s *SqliteProvider) query(clause string, args ...any) ([]Prompt, error) {
+
+	rows, err := s.db.Query(
+		`SELECT p.id, p.name, p.title, p.description, p.arguments, p.messages, p.content, CAST(p.revision AS TEXT), p.extra, p.partial, p.extends,
+			(SELECT json_group_array(tag) FROM (SELECT tag FROM prompt_tags WHERE prompt_id = p.id ORDER BY position))
+		FROM prompts p `+clause, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	prompts := []Prompt{}
+
+	for rows.Next() {
+
+		var prompt Prompt
+		var arguments, messages, extra, tags string
+
+		err = rows.Scan(&prompt.Id, &prompt.Name, &prompt.Title, &prompt.Description, &arguments, &messages, &prompt.Content, &prompt.Revision, &extra, &prompt.Partial, &prompt.Extends, &tags)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err = json.Unmarshal([]byte(arguments), &prompt.Arguments); err != nil {
+			return nil, fmt.Errorf("invalid arguments of prompt %s: %w", prompt.Id, err)
+		}
+
+		if err = json.Unmarshal([]byte(messages), &prompt.Messages); err != nil {
+			return nil, fmt.Errorf("invalid messages of prompt %s: %w", prompt.Id, err)
+		}
+
+		if err = json.Unmarshal([]byte(extra), &prompt.Extra); err != nil {
+			return nil, fmt.Errorf("invalid extra metadata of prompt %s: %w", prompt.Id, err)
+		}
+
+		if err = json.Unmarshal([]byte(tags), &prompt.Tags); err != nil {
+			return nil, fmt.Errorf("invalid tags of prompt %s: %w", prompt.Id, err)
+		}
+
+		// Keep empty lists and maps nil like the other providers do
+		if len(prompt.Tags) == 0 {
+			prompt.Tags = nil
+		}
+
+		if len(prompt.Messages) == 0 {
+			prompt.Messages = nil
+		}
+
+		if len(prompt.Extra) == 0 {
+			prompt.Extra = nil
+		}
+
+		prompts = append(prompts, prompt)
+	}
+
+	return prompts, rows.Err()
+}
+
+// promptColumns holds the fields of the prompt stored as JSON
+type promptColumns struct {
+	arguments string
+	messages  string
+	extra     string
+}
+
+// marshalColumns encodes the fields of the prompt stored as JSON
+func marshalColumns(prompt Prompt) (promptColumns, error) {
+
+	var columns promptColumns
+
+	arguments, err := json.Marshal(prompt.Arguments)
+
+	if err != nil {
+		return columns, err
+	}
+
+	messages := []byte("[]")
+
+	if len(prompt.Messages) > 0 {
+		if messages, err = json.Marshal(prompt.Messages); err != nil {
+			return columns, err
+		}
+	}
+
+	extra := []byte("{}")
+
+	if len(prompt.Extra) > 0 {
+		if extra, err = json.Marshal(prompt.Extra); err != nil {
+			return columns, fmt.Errorf("extra metadata can not be stored as JSON: %w", err)
+		}
+	}
+
+	columns.arguments = string(arguments)
+	columns.messages = string(messages)
+	columns.extra = string(extra)
+
+	return columns, nil
+}
+
+// saveTags replaces the tags of the prompt
+func saveTags(tx *sql.Tx, prompt Prompt) error {
+
+	if _, err := tx.Exec(`DELETE FROM prompt_tags WHERE prompt_id = ?`, prompt.Id); err != nil {
+		return err
+	}
+
+	for position, tag := range prompt.Tags {
+		if _, err := tx.Exec(`INSERT INTO prompt_tags (prompt_id, position, tag) VALUES (?, ?, ?)`, prompt.Id, position, tag); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// staleOrMissing tells why updating the prompt changed nothing
+func staleOrMissing(tx *sql.Tx, prompt Prompt) error {
+
+	var revision string
+
+	err := tx.QueryRow(`SELECT CAST(revision AS TEXT) FROM prompts WHERE id = ?`, prompt.Id).Scan(&revision)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return fmt.Errorf("%w: expected revision %s, the current revision is %s", ErrConflict, prompt.Revision, revision)
+}
+
+func promptExists(tx *sql.Tx, promptId string) (bool, error) {
+
+	var exists bool
+
+	err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM prompts WHERE id = ?)`, promptId).Scan(&exists)
+
+	return exists, err
+}
+
+// migrate applies the migrations the database has not seen yet
+func migrate(db *sql.DB, p *plog.Plogger) error {
+
+	tx, err := db.Begin()
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	var version int
+
+	if err = tx.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+
+	if version > len(migrations) {
+		return fmt.Errorf("prompts database schema version %d is newer than the supported version %d", version, len(migrations))
+	}
+
+	for i, migration := range migrations[version:] {
+
+		p.Write(plog.SERVER, fmt.Sprintf("migrating prompts database schema to version %d", version+i+1))
+
+		if _, err = tx.Exec(migration); err != nil {
+			return fmt.Errorf("prompts database migration %d failed: %w", version+i+1, err)
+		}
+	}
+
+	// PRAGMA does not accept bound parameters
+	if _, err = tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, len(migrations))); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// escapeGlob escapes the GLOB wildcards so the value is matched literally
+func escapeGlob(value string) string {
+
+	var sb strings.Builder
+
+	for _, r := range value {
+		switch r {
+		case '*', '?', '[':
+			sb.WriteString("[" + string(r) + "]")
+		default:
+			sb.WriteRune(r)
+		}
+	}
+
+	return sb.String()
+}
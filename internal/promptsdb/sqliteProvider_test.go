@@ -0,0 +1,494 @@
+package promptsdb
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func newTestSqliteProvider(t *testing.T) (*SqliteProvider, string) {
+	t.Helper()
+
+	dbPath := filepath.Join(t.TempDir(), "prompts.db")
+
+	provider, err := NewPromptsSqliteProvider(dbPath, filepath.Join(t.TempDir(), "test.log"))
+	if err != nil {
+		t.Fatalf("Failed to create SqliteProvider: %v", err)
+	}
+
+	t.Cleanup(func() { provider.Close() })
+
+	return provider, dbPath
+}
+
+func TestSqliteProviderCreateAndRead(t *testing.T) {
+	provider, _ := newTestSqliteProvider(t)
+
+	prompt := Prompt{
+		Name:        "test-prompt",
+		Title:       "Test Prompt",
+		Description: "A test prompt for unit testing",
+		Arguments:   []Argument{{Name: "name", Required: true, Examples: []string{"Ada"}}, {Name: "age"}},
+		Messages:    []Message{{Role: ROLE_USER, Content: "Hi"}, {Role: ROLE_ASSISTANT, Content: "Hello!"}},
+		Content:     "Hello {{.name}}, you are {{.age}} years old.",
+		Tags:        []string{"test", "example"},
+		Extra:       map[string]any{"owner": "platform-team"},
+	}
+
+	if err := provider.Create(prompt); err != nil {
+		t.Fatalf("Failed to create prompt: %v", err)
+	}
+
+	retrievedPrompt, err := provider.Read("test-prompt")
+	if err != nil {
+		t.Fatalf("Failed to read prompt: %v", err)
+	}
+
+	if retrievedPrompt.Id != "test-prompt" {
+		t.Errorf("Expected id test-prompt, got %s", retrievedPrompt.Id)
+	}
+	if retrievedPrompt.Title != prompt.Title {
+		t.Errorf("Expected title %s, got %s", prompt.Title, retrievedPrompt.Title)
+	}
+	if retrievedPrompt.Description != prompt.Description {
+		t.Errorf("Expected description %s, got %s", prompt.Description, retrievedPrompt.Description)
+	}
+	if retrievedPrompt.Content != prompt.Content {
+		t.Errorf("Expected content %s, got %s", prompt.Content, retrievedPrompt.Content)
+	}
+	if len(retrievedPrompt.Arguments) != 2 || !reflect.DeepEqual(retrievedPrompt.Arguments[0], prompt.Arguments[0]) {
+		t.Errorf("Expected arguments %v, got %v", prompt.Arguments, retrievedPrompt.Arguments)
+	}
+	if len(retrievedPrompt.Tags) != 2 || retrievedPrompt.Tags[0] != "test" || retrievedPrompt.Tags[1] != "example" {
+		t.Errorf("Expected tags %v in order, got %v", prompt.Tags, retrievedPrompt.Tags)
+	}
+	if len(retrievedPrompt.Messages) != 2 || retrievedPrompt.Messages[1] != prompt.Messages[1] {
+		t.Errorf("Expected messages %v, got %v", prompt.Messages, retrievedPrompt.Messages)
+	}
+	if retrievedPrompt.Extra["owner"] != "platform-team" {
+		t.Errorf("Expected extra metadata %v, got %v", prompt.Extra, retrievedPrompt.Extra)
+	}
+
+	// Names are unique
+	if err = provider.Create(prompt); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("Expected ErrAlreadyExists when creating a prompt with an existing name, got %v", err)
+	}
+
+	if _, err = provider.Read("nonexistent"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound when reading non-existent prompt, got %v", err)
+	}
+
+	if err = provider.Create(Prompt{Name: ""}); !errors.Is(err, ErrInvalidPrompt) {
+		t.Errorf("Expected ErrInvalidPrompt when creating a prompt without a name, got %v", err)
+	}
+
+	if err = provider.Create(Prompt{Name: "system-prompt", Messages: []Message{{Role: "system"}}}); !errors.Is(err, ErrInvalidPrompt) {
+		t.Errorf("Expected ErrInvalidPrompt when creating a prompt with an unknown message role, got %v", err)
+	}
+}
+
+func TestSqliteProviderUpdate(t *testing.T) {
+	provider, _ := newTestSqliteProvider(t)
+
+	if err := provider.Create(Prompt{Name: "updated", Title: "Before", Tags: []string{"old"}}); err != nil {
+		t.Fatalf("Failed to create prompt: %v", err)
+	}
+
+	if err := provider.Update(Prompt{Name: "updated", Title: "After", Content: "New", Tags: []string{"new", "tags"}}); err != nil {
+		t.Fatalf("Failed to update prompt: %v", err)
+	}
+
+	prompt, err := provider.Read("updated")
+	if err != nil {
+		t.Fatalf("Failed to read prompt: %v", err)
+	}
+
+	if prompt.Title != "After" || prompt.Content != "New" {
+		t.Errorf("Expected updated title and content, got %+v", prompt)
+	}
+	if len(prompt.Tags) != 2 || prompt.Tags[0] != "new" {
+		t.Errorf("Expected tags to be replaced, got %v", prompt.Tags)
+	}
+
+	// Only the current revision can be updated
+	if err = provider.Update(Prompt{Name: "updated", Title: "Stale", Revision: "1"}); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected ErrConflict when updating a stale revision, got %v", err)
+	}
+
+	if err = provider.Update(Prompt{Name: "updated", Title: "Current", Revision: prompt.Revision}); err != nil {
+		t.Errorf("Failed to update prompt with the current revision %s: %v", prompt.Revision, err)
+	}
+
+	if prompt, _ = provider.Read("updated"); prompt.Revision != "3" || prompt.Title != "Current" {
+		t.Errorf("Expected revision 3 with the current title, got %+v", prompt)
+	}
+
+	if err = provider.Update(Prompt{Name: "nonexistent", Revision: "1"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound when updating non-existent prompt with a revision, got %v", err)
+	}
+
+	if err = provider.Update(Prompt{Name: "nonexistent"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound when updating non-existent prompt, got %v", err)
+	}
+}
+
+func TestSqliteProviderDelete(t *testing.T) {
+	provider, dbPath := newTestSqliteProvider(t)
+
+	if err := provider.Create(Prompt{Name: "deleted", Tags: []string{"gone"}}); err != nil {
+		t.Fatalf("Failed to create prompt: %v", err)
+	}
+
+	if err := provider.Delete("deleted"); err != nil {
+		t.Fatalf("Failed to delete prompt: %v", err)
+	}
+
+	if _, err := provider.Read("deleted"); err == nil {
+		t.Error("Expected error when reading deleted prompt")
+	}
+
+	if err := provider.Delete("deleted"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound when deleting non-existent prompt, got %v", err)
+	}
+
+	// Tags of the prompt are removed as well
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	var tags int
+	if err = db.QueryRow(`SELECT count(*) FROM prompt_tags`).Scan(&tags); err != nil {
+		t.Fatalf("Failed to count tags: %v", err)
+	}
+	if tags != 0 {
+		t.Errorf("Expected tags of deleted prompt to be removed, found %d", tags)
+	}
+}
+
+func TestSqliteProviderList(t *testing.T) {
+	provider, _ := newTestSqliteProvider(t)
+
+	prompts := []Prompt{
+		{Name: "review_code", Tags: []string{"review"}},
+		{Name: "describe_tampere", Tags: []string{"sample", "finland"}},
+		{Name: "review_docs", Tags: []string{"review", "docs"}},
+		{Name: "daily_report"},
+		{Name: "glob*chars"},
+		{Name: "ops/deploy"},
+		{Name: "ops/db/backup"},
+	}
+
+	for _, prompt := range prompts {
+		if err := provider.Create(prompt); err != nil {
+			t.Fatalf("Failed to create prompt %s: %v", prompt.Name, err)
+		}
+	}
+
+	tests := []struct {
+		query    PromptQuery
+		expected []string
+		total    int
+	}{
+		{PromptQuery{}, []string{"daily_report", "describe_tampere", "glob*chars", "ops/db/backup", "ops/deploy", "review_code", "review_docs"}, 7},
+		{PromptQuery{All: true, NameStartsWith: "x", IndexTo: 1}, []string{"daily_report", "describe_tampere", "glob*chars", "ops/db/backup", "ops/deploy", "review_code", "review_docs"}, 7},
+		{PromptQuery{NameStartsWith: "review"}, []string{"review_code", "review_docs"}, 2},
+		{PromptQuery{NameStartsWith: "glob*"}, []string{"glob*chars"}, 1},
+		{PromptQuery{NameStartsWith: "g*"}, []string{}, 0},
+		{PromptQuery{NameContains: "_d"}, []string{"review_docs"}, 1},
+		{PromptQuery{Tag: "review"}, []string{"review_code", "review_docs"}, 2},
+		{PromptQuery{Tag: "review", NameContains: "code"}, []string{"review_code"}, 1},
+		{PromptQuery{Namespace: "ops"}, []string{"ops/db/backup", "ops/deploy"}, 2},
+		{PromptQuery{Namespace: "ops/db/"}, []string{"ops/db/backup"}, 1},
+		{PromptQuery{Namespace: "op"}, []string{}, 0},
+		{PromptQuery{IndexFrom: 1, IndexTo: 3}, []string{"describe_tampere", "glob*chars"}, 7},
+		{PromptQuery{IndexFrom: 6}, []string{"review_docs"}, 7},
+		{PromptQuery{IndexFrom: 3, IndexTo: 1}, []string{}, 7},
+	}
+
+	for _, test := range tests {
+		results, total, err := provider.List(test.query)
+		if err != nil {
+			t.Fatalf("Failed to list prompts with query %+v: %v", test.query, err)
+		}
+
+		if total != test.total {
+			t.Errorf("Expected total %d for query %+v, got %d", test.total, test.query, total)
+		}
+
+		if len(results) != len(test.expected) {
+			t.Errorf("Expected %d prompts for query %+v, got %d", len(test.expected), test.query, len(results))
+			continue
+		}
+
+		for i, name := range test.expected {
+			if results[i].Name != name {
+				t.Errorf("Expected prompt %s at index %d for query %+v, got %s", name, i, test.query, results[i].Name)
+			}
+		}
+
+		// The filesystem provider gives the same answer for the same query
+		applied, appliedTotal := test.query.Apply(prompts)
+		if appliedTotal != total || len(applied) != len(results) {
+			t.Errorf("Expected PromptQuery.Apply to agree for query %+v, got %d (total %d)", test.query, len(applied), appliedTotal)
+		}
+	}
+}
+
+func TestSqliteProviderPartials(t *testing.T) {
+	provider, _ := newTestSqliteProvider(t)
+
+	if err := provider.Create(Prompt{Name: "shared/style_guide", Content: "Be concise", Partial: true}); err != nil {
+		t.Fatalf("Failed to create partial: %v", err)
+	}
+
+	if err := provider.Create(Prompt{Name: "review", Content: "{{include \"shared/style_guide\"}}"}); err != nil {
+		t.Fatalf("Failed to create prompt: %v", err)
+	}
+
+	partial, err := provider.Read("shared/style_guide")
+	if err != nil {
+		t.Fatalf("Failed to read partial: %v", err)
+	}
+
+	if !partial.Partial {
+		t.Error("Expected the partial to be marked as partial")
+	}
+
+	results, total, err := provider.List(PromptQuery{NoPartials: true})
+	if err != nil {
+		t.Fatalf("Failed to list prompts: %v", err)
+	}
+
+	if total != 1 || len(results) != 1 || results[0].Name != "review" {
+		t.Errorf("Expected only the prompt review without partials, got %d prompts (total %d)", len(results), total)
+	}
+
+	// Partials are listed unless asked to leave them out
+	if _, total, _ = provider.List(PromptQuery{}); total != 2 {
+		t.Errorf("Expected 2 prompts with partials, got %d", total)
+	}
+
+	partial.Partial = false
+	if err = provider.Update(partial); err != nil {
+		t.Fatalf("Failed to update partial: %v", err)
+	}
+
+	if _, total, _ = provider.List(PromptQuery{NoPartials: true}); total != 2 {
+		t.Errorf("Expected 2 prompts after unmarking the partial, got %d", total)
+	}
+}
+
+func TestSqliteProviderExtends(t *testing.T) {
+	provider, _ := newTestSqliteProvider(t)
+
+	child := Prompt{Name: "security_review", Extends: "base_review", Content: `{{define "focus"}}Look for injections.{{end}}`}
+
+	if err := provider.Create(child); err != nil {
+		t.Fatalf("Failed to create prompt: %v", err)
+	}
+
+	stored, err := provider.Read("security_review")
+	if err != nil {
+		t.Fatalf("Failed to read prompt: %v", err)
+	}
+
+	if stored.Extends != "base_review" {
+		t.Errorf("Expected the prompt to extend base_review, got %q", stored.Extends)
+	}
+
+	stored.Extends = ""
+	if err = provider.Update(stored); err != nil {
+		t.Fatalf("Failed to update prompt: %v", err)
+	}
+
+	if stored, _ = provider.Read("security_review"); stored.Extends != "" {
+		t.Errorf("Expected the prompt to extend nothing after the update, got %q", stored.Extends)
+	}
+
+	// A prompt extending itself is refused
+	if err = provider.Create(Prompt{Name: "loop", Extends: "loop"}); !errors.Is(err, ErrInvalidPrompt) {
+		t.Errorf("Expected ErrInvalidPrompt for a prompt extending itself, got %v", err)
+	}
+}
+
+func TestSqliteProviderTypedArguments(t *testing.T) {
+	provider, _ := newTestSqliteProvider(t)
+
+	prompt := typedPrompt()
+
+	if err := provider.Create(prompt); err != nil {
+		t.Fatalf("Failed to create prompt: %v", err)
+	}
+
+	stored, err := provider.Read("typed")
+	if err != nil {
+		t.Fatalf("Failed to read prompt: %v", err)
+	}
+
+	// Stored arguments resolve like the declared ones, JSON numbers become float64
+	resolved, err := stored.ResolveArguments(map[string]string{"verbose": "true", "ids": "4"})
+	if err != nil {
+		t.Fatalf("ResolveArguments() returned error: %v", err)
+	}
+
+	if resolved["verbose"] != true || resolved["limit"] != 3.0 || !reflect.DeepEqual(resolved["ids"], []any{4}) {
+		t.Errorf("Expected the stored argument types, got %#v", resolved)
+	}
+
+	if _, err = stored.ResolveArguments(map[string]string{"limit": "11"}); err == nil {
+		t.Error("Expected the stored maximum to reject 11")
+	}
+
+	// Invalid argument declarations are refused
+	if err = provider.Create(Prompt{Name: "broken", Arguments: []Argument{{Name: "mode", Type: ARGUMENT_ENUM}}}); !errors.Is(err, ErrInvalidPrompt) {
+		t.Errorf("Expected ErrInvalidPrompt for an enum without values, got %v", err)
+	}
+}
+
+func TestSqliteProviderMigrationsAndReopen(t *testing.T) {
+	provider, dbPath := newTestSqliteProvider(t)
+
+	if err := provider.Create(Prompt{Name: "persisted", Content: "Persisted content"}); err != nil {
+		t.Fatalf("Failed to create prompt: %v", err)
+	}
+	provider.Close()
+
+	// Reopening an up to date database applies no migrations and keeps the data
+	reopened, err := NewPromptsSqliteProvider(dbPath, filepath.Join(t.TempDir(), "test.log"))
+	if err != nil {
+		t.Fatalf("Failed to reopen SqliteProvider: %v", err)
+	}
+	defer reopened.Close()
+
+	var version int
+	if err = reopened.db.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		t.Fatalf("Failed to read schema version: %v", err)
+	}
+	if version != len(migrations) {
+		t.Errorf("Expected schema version %d, got %d", len(migrations), version)
+	}
+
+	prompt, err := reopened.Read("persisted")
+	if err != nil {
+		t.Fatalf("Failed to read persisted prompt: %v", err)
+	}
+	if prompt.Content != "Persisted content" {
+		t.Errorf("Expected content 'Persisted content', got '%s'", prompt.Content)
+	}
+}
+
+func TestSqliteProviderPathWithURICharacters(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "team prompts?#50%.db")
+
+	provider, err := NewPromptsSqliteProvider(dbPath, filepath.Join(t.TempDir(), "test.log"))
+	if err != nil {
+		t.Fatalf("Failed to create SqliteProvider: %v", err)
+	}
+	defer provider.Close()
+
+	if err = provider.Create(Prompt{Name: "escaped", Content: "Content"}); err != nil {
+		t.Fatalf("Failed to create prompt: %v", err)
+	}
+
+	// The database is created at the path as given, not at a path cut at the ?
+	if _, err = os.Stat(dbPath); err != nil {
+		t.Errorf("Expected database file at %s: %v", dbPath, err)
+	}
+}
+
+func TestSqliteProviderListErrors(t *testing.T) {
+	provider, _ := newTestSqliteProvider(t)
+	provider.Close()
+
+	_, _, err := provider.List(PromptQuery{All: true})
+
+	var dbErr *PromptsDBError
+	if !errors.As(err, &dbErr) || dbErr.Op != "list" {
+		t.Errorf("Expected PromptsDBError for list, got %v", err)
+	}
+}
+
+func TestSqliteProviderRejectsNewerSchema(t *testing.T) {
+	provider, dbPath := newTestSqliteProvider(t)
+
+	if _, err := provider.db.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, len(migrations)+1)); err != nil {
+		t.Fatalf("Failed to set schema version: %v", err)
+	}
+	provider.Close()
+
+	if _, err := NewPromptsSqliteProvider(dbPath, filepath.Join(t.TempDir(), "test.log")); err == nil {
+		t.Error("Expected error when opening a database with a newer schema")
+	}
+}
+
+func TestSqliteProviderConcurrentWriters(t *testing.T) {
+	provider, dbPath := newTestSqliteProvider(t)
+
+	// A second provider stands in for another prompter instance sharing the file
+	other, err := NewPromptsSqliteProvider(dbPath, filepath.Join(t.TempDir(), "test.log"))
+	if err != nil {
+		t.Fatalf("Failed to open second SqliteProvider: %v", err)
+	}
+	defer other.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+
+	for i := range 20 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			errs <- provider.Create(Prompt{Name: fmt.Sprintf("first-%d", i), Tags: []string{"first"}})
+		}()
+		go func() {
+			defer wg.Done()
+			errs <- other.Create(Prompt{Name: fmt.Sprintf("second-%d", i), Tags: []string{"second"}})
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Concurrent create failed: %v", err)
+		}
+	}
+
+	_, total, err := provider.List(PromptQuery{})
+	if err != nil {
+		t.Fatalf("Failed to list prompts: %v", err)
+	}
+	if total != 40 {
+		t.Errorf("Expected 40 prompts, got %d", total)
+	}
+}
+
+func TestNewWithSqliteProvider(t *testing.T) {
+	tempDir := t.TempDir()
+
+	config := ProviderConfiguration{
+		Provider: SQLITE_PROVIDER,
+		Sqlite: SqliteProviderConfiguration{
+			Path: filepath.Join(tempDir, "nested", "prompts.db"),
+		},
+	}
+
+	provider, err := New(SQLITE_PROVIDER, config, filepath.Join(tempDir, "test.log"))
+	if err != nil {
+		t.Fatalf("Failed to create provider: %v", err)
+	}
+
+	sqliteProvider, ok := provider.(*SqliteProvider)
+	if !ok {
+		t.Fatalf("Expected *SqliteProvider, got %T", provider)
+	}
+	sqliteProvider.Close()
+}
@@ -3,6 +3,9 @@ package server
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"reflect"
+	"sync"
 
 	"github.com/hkionline/prompter/internal/configuration"
 	"github.com/hkionline/prompter/internal/plog"
@@ -12,6 +15,10 @@ import (
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
+const (
+	METHOD_LIST_PROMPTS = "prompts/list" // MCP method served by the prompt handler instead of the SDK defaults
+)
+
 // Prompter implements the MCP server using the official Go SDK
 type Prompter struct {
 	version string
@@ -21,71 +28,213 @@ type Prompter struct {
 	server  *mcp.Server
 	prompts *prompts.PromptHandler
 	tools   *tools.ToolHandler
+
+	mu         sync.Mutex
+	registered map[string]*mcp.Prompt   // prompts currently registered to the MCP server identified by name
+	resources  map[string]*mcp.Resource // prompts currently published as resources identified by name
 }
 
 // New creates a new SDKServer instance
 func New(version string, config *configuration.Configuration, logger *plog.Plogger, db promptsdb.Provider) *Prompter {
 	return &Prompter{
-		version: version,
-		config:  config,
-		logger:  logger,
-		db:      db,
+		version:    version,
+		config:     config,
+		logger:     logger,
+		db:         db,
+		registered: map[string]*mcp.Prompt{},
+		resources:  map[string]*mcp.Resource{},
 	}
 }
 
 // Run starts the MCP server with the configured transport
 func (s *Prompter) Run(ctx context.Context) error {
+
+	s.setup()
+
+	// Create transport based on configuration
+	s.logger.Write(plog.SERVER, "creating transport: %s", s.config.Transport.Type)
+	trans, err := newTransport(s.config.Transport.Type)
+	if err != nil {
+		return fmt.Errorf("failed to create transport: %w", err)
+	}
+
+	s.logger.Write(plog.SERVER, "starting the MCP server with %s transport", s.config.Transport.Type)
+	return trans.start(ctx, s.server, s.config)
+}
+
+// setup creates the MCP server instance and attaches the capability handlers to it
+func (s *Prompter) setup() {
 	s.logger.Write(plog.SERVER, "initializing prompter MCP-server")
 
-	// Create MCP server instance
-	server := mcp.NewServer("prompter", s.version, &mcp.ServerOptions{})
+	// Initialize handlers
+	s.prompts = prompts.NewPromptHandler(s.db, s.config.Prompts, s.logger)
+	s.tools = tools.NewToolHandler(s.db, s.prompts, s.logger)
+
+	// Create MCP server instance, the SDK advertises the listChanged capability for prompts
+	server := mcp.NewServer("prompter", s.version, &mcp.ServerOptions{
+		CompletionHandler: s.prompts.HandleComplete,
+	})
 
 	s.server = server
 
 	s.logger.Write(plog.SERVER, "attaching capability handlers to the server")
 
-	// Initialize handlers
-	s.prompts = prompts.NewPromptHandler(s.db, s.logger)
-	s.tools = tools.NewToolHandler(s.db, s.logger)
+	// Serve prompts/list from the storage provider to support cursor based paging
+	s.server.AddReceivingMiddleware(s.listPromptsMiddleware)
 
-	// Add all prompts from the database to the server
-	promptsList, err := s.db.List(promptsdb.PromptQuery{All: true})
-	if err != nil {
-		s.logger.Write(plog.SERVER, "failed to load prompts for registration: %s", err.Error())
-	} else {
-		for _, prompt := range promptsList {
-			s.server.AddPrompts(
-				&mcp.ServerPrompt{
-					Prompt: &mcp.Prompt{
-						Name:        prompt.Name,
-						Title:       prompt.Title,
-						Description: prompt.Description,
-					},
-					Handler: s.prompts.HandleGet,
-				},
-			)
-		}
+	// Any prompt can be read as a resource through the template, also with its arguments rendered
+	s.server.AddResourceTemplates(&mcp.ServerResourceTemplate{
+		ResourceTemplate: prompts.ResourceTemplate(),
+		Handler:          s.prompts.HandleReadResource,
+	})
+
+	// Add all prompts from the database to the server and keep them in sync
+	s.syncPrompts()
+
+	if notifier, ok := s.db.(promptsdb.Notifier); ok {
+		notifier.Subscribe(func(change promptsdb.Change) {
+			s.logger.Write(plog.SERVER, "prompt "+change.PromptId+" "+change.Type)
+			s.syncPrompts()
+		})
 	}
 
-	// Add tools to the server
-	tool := s.tools.CreatePromptTool()
+	// Add tools to the server, the tool handler dispatches the calls by tool name
+	serverTools := []*mcp.ServerTool{}
 
-	s.server.AddTools(
-		&mcp.ServerTool{
+	for _, tool := range s.tools.Tools() {
+		serverTools = append(serverTools, &mcp.ServerTool{
 			Tool:    tool,
 			Handler: s.tools.HandleCall,
-		},
-	)
+		})
+	}
 
-	// Create transport based on configuration
-	s.logger.Write(plog.SERVER, "creating transport: %s", s.config.Transport.Type)
-	trans, err := newTransport(s.config.Transport.Type)
+	s.server.AddTools(serverTools...)
+}
+
+// syncPrompts registers the prompts of the database to the MCP server and unregisters
+// the ones no longer there. The SDK sends notifications/prompts/list_changed to the
+// connected clients whenever the registered prompts change, and
+// notifications/resources/list_changed whenever the published resources change.
+func (s *Prompter) syncPrompts() {
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	promptsList, _, err := s.db.List(promptsdb.PromptQuery{All: true})
 	if err != nil {
-		return fmt.Errorf("failed to create transport: %w", err)
+		s.logger.Write(plog.SERVER, "failed to load prompts for registration: %s", err.Error())
+		return
 	}
 
-	s.logger.Write(plog.SERVER, "starting the MCP server with %s transport", s.config.Transport.Type)
-	return trans.start(ctx, server, s.config)
+	added := []*mcp.ServerPrompt{}
+	current := map[string]bool{}
+
+	for _, prompt := range promptsList {
+
+		// Partials are only included in other prompts and clients can not get them
+		if prompt.Partial {
+			continue
+		}
+
+		current[prompt.Name] = true
+
+		mcpPrompt := prompts.ToMCPPrompt(prompt)
+
+		if registered, ok := s.registered[prompt.Name]; ok && reflect.DeepEqual(registered, mcpPrompt) {
+			continue
+		}
+
+		s.registered[prompt.Name] = mcpPrompt
+
+		added = append(added, &mcp.ServerPrompt{
+			Prompt:  mcpPrompt,
+			Handler: s.prompts.HandleGet,
+		})
+	}
+
+	removed := []string{}
+
+	for name := range s.registered {
+		if !current[name] {
+			delete(s.registered, name)
+			removed = append(removed, name)
+		}
+	}
+
+	s.server.AddPrompts(added...)
+
+	if len(removed) > 0 {
+		s.server.RemovePrompts(removed...)
+	}
+
+	s.syncResources(promptsList)
+}
+
+// syncResources publishes the prompts as resources and removes the ones no longer there.
+// The resource of a prompt carries its revision, so it is published again whenever the
+// prompt changes and clients are sent notifications/resources/list_changed.
+func (s *Prompter) syncResources(promptsList []promptsdb.Prompt) {
+
+	added := []*mcp.ServerResource{}
+	current := map[string]bool{}
+
+	for _, prompt := range promptsList {
+
+		// Partials are not published, like prompts/list leaves them out
+		if prompt.Partial {
+			continue
+		}
+
+		resource := prompts.ToMCPResource(prompt)
+
+		// The SDK refuses resources without a valid URI, e.g. names with spaces
+		if _, err := url.Parse(resource.URI); err != nil {
+			s.logger.Write(plog.SERVER, "prompt %s can not be published as a resource: %s", prompt.Name, err.Error())
+			continue
+		}
+
+		current[prompt.Name] = true
+
+		if published, ok := s.resources[prompt.Name]; ok && reflect.DeepEqual(published, resource) {
+			continue
+		}
+
+		s.resources[prompt.Name] = resource
+
+		added = append(added, &mcp.ServerResource{
+			Resource: resource,
+			Handler:  s.prompts.HandleReadResource,
+		})
+	}
+
+	removed := []string{}
+
+	for name, resource := range s.resources {
+		if !current[name] {
+			delete(s.resources, name)
+			removed = append(removed, resource.URI)
+		}
+	}
+
+	s.server.AddResources(added...)
+
+	if len(removed) > 0 {
+		s.server.RemoveResources(removed...)
+	}
+}
+
+// listPromptsMiddleware routes prompts/list requests to the prompt handler
+func (s *Prompter) listPromptsMiddleware(next mcp.MethodHandler[*mcp.ServerSession]) mcp.MethodHandler[*mcp.ServerSession] {
+	return func(ctx context.Context, ss *mcp.ServerSession, method string, params mcp.Params) (mcp.Result, error) {
+
+		if method != METHOD_LIST_PROMPTS {
+			return next(ctx, ss, method, params)
+		}
+
+		req, _ := params.(*mcp.ListPromptsParams)
+
+		return s.prompts.HandleList(ctx, ss, req)
+	}
 }
 
 // GetServer returns the underlying MCP server instance
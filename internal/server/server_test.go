@@ -1,11 +1,21 @@
 package server
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/hkionline/prompter/internal/configuration"
 	"github.com/hkionline/prompter/internal/plog"
+	"github.com/hkionline/prompter/internal/prompts"
 	"github.com/hkionline/prompter/internal/promptsdb"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -25,7 +35,7 @@ func (m *MockDB) Read(name string) (promptsdb.Prompt, error) {
 	}, nil
 }
 
-func (m *MockDB) List(query promptsdb.PromptQuery) ([]promptsdb.Prompt, error) {
+func (m *MockDB) List(query promptsdb.PromptQuery) ([]promptsdb.Prompt, int, error) {
 	return []promptsdb.Prompt{
 		{
 			Name:        "test1",
@@ -37,7 +47,7 @@ func (m *MockDB) List(query promptsdb.PromptQuery) ([]promptsdb.Prompt, error) {
 			Title:       "Test 2",
 			Description: "Description 2",
 		},
-	}, nil
+	}, 2, nil
 }
 
 func (m *MockDB) Update(prompt promptsdb.Prompt) error {
@@ -100,3 +110,403 @@ func TestServerStart(t *testing.T) {
 	assert.NotNil(t, server)
 	assert.Equal(t, "stdio", config.Transport.Type)
 }
+
+// NotifyingDB is an in-memory promptsdb.Provider which reports its changes
+type NotifyingDB struct {
+	mu        sync.Mutex
+	prompts   map[string]promptsdb.Prompt
+	listeners []func(change promptsdb.Change)
+}
+
+func (m *NotifyingDB) Create(prompt promptsdb.Prompt) error {
+	m.mu.Lock()
+	m.prompts[prompt.Name] = prompt
+	m.mu.Unlock()
+	m.notify(promptsdb.Change{Type: promptsdb.PROMPT_CREATED, PromptId: prompt.Name})
+	return nil
+}
+
+func (m *NotifyingDB) Read(name string) (promptsdb.Prompt, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if p, ok := m.prompts[name]; ok {
+		return p, nil
+	}
+	return promptsdb.Prompt{}, assert.AnError
+}
+
+func (m *NotifyingDB) List(query promptsdb.PromptQuery) ([]promptsdb.Prompt, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	results := []promptsdb.Prompt{}
+	for _, p := range m.prompts {
+		results = append(results, p)
+	}
+	page, total := query.Apply(results)
+	return page, total, nil
+}
+
+func (m *NotifyingDB) Update(prompt promptsdb.Prompt) error {
+	m.mu.Lock()
+	m.prompts[prompt.Name] = prompt
+	m.mu.Unlock()
+	m.notify(promptsdb.Change{Type: promptsdb.PROMPT_UPDATED, PromptId: prompt.Name})
+	return nil
+}
+
+func (m *NotifyingDB) Delete(name string) error {
+	m.mu.Lock()
+	delete(m.prompts, name)
+	m.mu.Unlock()
+	m.notify(promptsdb.Change{Type: promptsdb.PROMPT_DELETED, PromptId: name})
+	return nil
+}
+
+func (m *NotifyingDB) Subscribe(listener func(change promptsdb.Change)) {
+	m.listeners = append(m.listeners, listener)
+}
+
+func (m *NotifyingDB) notify(change promptsdb.Change) {
+	for _, listener := range m.listeners {
+		listener(change)
+	}
+}
+
+// connectClient sets up the server and connects an in-memory client to it
+func connectClient(t *testing.T, db promptsdb.Provider, changed chan struct{}) *mcp.ClientSession {
+	t.Helper()
+
+	return connectClientWithOptions(t, db, &mcp.ClientOptions{
+		PromptListChangedHandler: func(ctx context.Context, cs *mcp.ClientSession, params *mcp.PromptListChangedParams) {
+			changed <- struct{}{}
+		},
+	})
+}
+
+// connectClientWithOptions sets up the server and connects an in-memory client with the given options to it
+func connectClientWithOptions(t *testing.T, db promptsdb.Provider, options *mcp.ClientOptions) *mcp.ClientSession {
+	t.Helper()
+
+	return connectClientLogging(t, db, options, nil)
+}
+
+// connectClientLogging connects an in-memory client like connectClientWithOptions, the messages
+// the server sends are written to wire when it is given
+func connectClientLogging(t *testing.T, db promptsdb.Provider, options *mcp.ClientOptions, wire io.Writer) *mcp.ClientSession {
+	t.Helper()
+
+	config := &configuration.Configuration{
+		Transport: configuration.TransportConfiguration{Type: "stdio"},
+		LogFile:   "/tmp/test.log",
+	}
+
+	prompter := New("0.5.0", config, plog.New("/tmp/test.log"), db)
+	prompter.setup()
+
+	var serverTransport mcp.Transport
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+
+	if wire != nil {
+		serverTransport = mcp.NewLoggingTransport(serverTransport, wire)
+	}
+
+	ctx := context.Background()
+
+	serverSession, err := prompter.GetServer().Connect(ctx, serverTransport)
+	if err != nil {
+		t.Fatalf("Failed to connect server: %v", err)
+	}
+	t.Cleanup(func() { serverSession.Close() })
+
+	client := mcp.NewClient("test-client", "1.0.0", options)
+
+	clientSession, err := client.Connect(ctx, clientTransport)
+	if err != nil {
+		t.Fatalf("Failed to connect client: %v", err)
+	}
+	t.Cleanup(func() { clientSession.Close() })
+
+	return clientSession
+}
+
+func waitForListChanged(t *testing.T, changed chan struct{}) {
+	t.Helper()
+
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected notifications/prompts/list_changed")
+	}
+}
+
+func TestPromptListChangedNotifications(t *testing.T) {
+	db := &NotifyingDB{prompts: map[string]promptsdb.Prompt{
+		"existing": {Name: "existing", Title: "Existing", Content: "Existing content"},
+	}}
+	changed := make(chan struct{}, 10)
+
+	session := connectClient(t, db, changed)
+	ctx := context.Background()
+
+	result, err := session.ListPrompts(ctx, &mcp.ListPromptsParams{})
+	assert.NoError(t, err)
+	assert.Len(t, result.Prompts, 1)
+
+	// A created prompt becomes available to the client
+	err = db.Create(promptsdb.Prompt{Name: "created", Title: "Created", Content: "Created content"})
+	assert.NoError(t, err)
+	waitForListChanged(t, changed)
+
+	got, err := session.GetPrompt(ctx, &mcp.GetPromptParams{Name: "created"})
+	assert.NoError(t, err)
+	assert.Len(t, got.Messages, 1)
+
+	// Content changes do not change the list
+	err = db.Update(promptsdb.Prompt{Name: "created", Title: "Created", Content: "Changed content"})
+	assert.NoError(t, err)
+
+	// Metadata changes do
+	err = db.Update(promptsdb.Prompt{Name: "created", Title: "Renamed", Content: "Changed content"})
+	assert.NoError(t, err)
+	waitForListChanged(t, changed)
+	assert.Empty(t, changed)
+
+	// A deleted prompt is unregistered
+	err = db.Delete("created")
+	assert.NoError(t, err)
+	waitForListChanged(t, changed)
+
+	_, err = session.GetPrompt(ctx, &mcp.GetPromptParams{Name: "created"})
+	assert.Error(t, err)
+}
+
+// errorCode reads the JSON-RPC error code of an error received by the client
+func errorCode(err error) int64 {
+	for err != nil {
+		wire := reflect.ValueOf(err)
+		if wire.Kind() == reflect.Pointer && wire.Elem().Kind() == reflect.Struct {
+			if code := wire.Elem().FieldByName("Code"); code.Kind() == reflect.Int64 {
+				return code.Int()
+			}
+		}
+		err = errors.Unwrap(err)
+	}
+	return 0
+}
+
+func TestErrorCodesReachClient(t *testing.T) {
+	db := &NotifyingDB{prompts: map[string]promptsdb.Prompt{
+		"review": {Name: "review", Content: "{{.code}}", Arguments: []promptsdb.Argument{{Name: "code", Required: true}}},
+	}}
+
+	session := connectClient(t, db, make(chan struct{}, 10))
+	ctx := context.Background()
+
+	_, err := session.GetPrompt(ctx, &mcp.GetPromptParams{Name: "review"})
+	assert.Error(t, err)
+	assert.Equal(t, int64(prompts.CODE_INVALID_PARAMS), errorCode(err))
+
+	_, err = session.ListPrompts(ctx, &mcp.ListPromptsParams{Cursor: "not a cursor"})
+	assert.Error(t, err)
+	assert.Equal(t, int64(prompts.CODE_INVALID_PARAMS), errorCode(err))
+}
+
+// wireLog collects the messages a LoggingTransport writes from the server goroutine
+type wireLog struct {
+	mu    sync.Mutex
+	lines strings.Builder
+}
+
+func (w *wireLog) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lines.Write(p)
+}
+
+// wireError returns the JSON-RPC error of the response the server sent for the request id
+func (w *wireLog) wireError(id int64) (int64, string, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, line := range strings.Split(w.lines.String(), "\n") {
+		var response struct {
+			ID    int64 `json:"id"`
+			Error *struct {
+				Code    int64  `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+
+		data, ok := strings.CutPrefix(line, "write: ")
+		if !ok || json.Unmarshal([]byte(data), &response) != nil || response.ID != id || response.Error == nil {
+			continue
+		}
+
+		return response.Error.Code, response.Error.Message, true
+	}
+
+	return 0, "", false
+}
+
+func TestErrorCodesOnTheWire(t *testing.T) {
+	db := &NotifyingDB{prompts: map[string]promptsdb.Prompt{
+		"review": {Name: "review", Content: "{{.code}}", Arguments: []promptsdb.Argument{{Name: "code", Required: true}}},
+		"broken": {Name: "broken", Content: "{{.code"},
+	}}
+
+	// The codes are read from the JSON the server sends, so an SDK change breaking rpcError fails here
+	wire := &wireLog{}
+	session := connectClientLogging(t, db, nil, wire)
+	ctx := context.Background()
+
+	tests := []struct {
+		id      int64 // request id, the client numbers its requests from 1 with initialize first
+		call    func() error
+		code    int64
+		message string
+	}{
+		{2, func() error {
+			_, err := session.GetPrompt(ctx, &mcp.GetPromptParams{Name: "review"})
+			return err
+		}, prompts.CODE_INVALID_PARAMS, "prompt review is missing required arguments: code"},
+		{3, func() error {
+			_, err := session.GetPrompt(ctx, &mcp.GetPromptParams{Name: "broken"})
+			return err
+		}, prompts.CODE_TEMPLATE_ERROR, "failed to render content of prompt broken: line 1: unclosed action"},
+		{4, func() error {
+			_, err := session.ListPrompts(ctx, &mcp.ListPromptsParams{Cursor: "not a cursor"})
+			return err
+		}, prompts.CODE_INVALID_PARAMS, "invalid cursor"},
+	}
+
+	for _, test := range tests {
+		assert.Error(t, test.call())
+
+		var code int64
+		var message string
+		assert.Eventually(t, func() bool {
+			var ok bool
+			code, message, ok = wire.wireError(test.id)
+			return ok
+		}, 5*time.Second, 10*time.Millisecond, "response to request %d", test.id)
+
+		assert.Equal(t, test.code, code, test.message)
+		assert.Contains(t, message, test.message)
+	}
+}
+
+func TestPromptResources(t *testing.T) {
+	db := &NotifyingDB{prompts: map[string]promptsdb.Prompt{
+		"review/code": {Name: "review/code", Title: "Code review", Content: "Review {{.language}} code", Revision: "1"},
+	}}
+	changed := make(chan struct{}, 10)
+
+	session := connectClientWithOptions(t, db, &mcp.ClientOptions{
+		ResourceListChangedHandler: func(ctx context.Context, cs *mcp.ClientSession, params *mcp.ResourceListChangedParams) {
+			changed <- struct{}{}
+		},
+	})
+	ctx := context.Background()
+
+	resources, err := session.ListResources(ctx, &mcp.ListResourcesParams{})
+	assert.NoError(t, err)
+	assert.Len(t, resources.Resources, 1)
+	assert.Equal(t, "prompt://review/code", resources.Resources[0].URI)
+	assert.Equal(t, prompts.MIME_TYPE_MARKDOWN, resources.Resources[0].MIMEType)
+
+	templates, err := session.ListResourceTemplates(ctx, &mcp.ListResourceTemplatesParams{})
+	assert.NoError(t, err)
+	assert.Len(t, templates.ResourceTemplates, 1)
+	assert.Equal(t, prompts.RESOURCE_URI_TEMPLATE, templates.ResourceTemplates[0].URITemplate)
+
+	raw, err := session.ReadResource(ctx, &mcp.ReadResourceParams{URI: "prompt://review/code"})
+	assert.NoError(t, err)
+	assert.Len(t, raw.Contents, 1)
+	assert.Contains(t, raw.Contents[0].Text, "title: Code review")
+	assert.Contains(t, raw.Contents[0].Text, "Review {{.language}} code")
+
+	rendered, err := session.ReadResource(ctx, &mcp.ReadResourceParams{URI: "prompt://review/code?rendered&language=Go"})
+	assert.NoError(t, err)
+	assert.Len(t, rendered.Contents, 1)
+	assert.Equal(t, "Review Go code", rendered.Contents[0].Text)
+
+	// Changing the prompt publishes the resource again
+	err = db.Update(promptsdb.Prompt{Name: "review/code", Title: "Code review", Content: "Review carefully", Revision: "2"})
+	assert.NoError(t, err)
+	waitForListChanged(t, changed)
+
+	err = db.Delete("review/code")
+	assert.NoError(t, err)
+	waitForListChanged(t, changed)
+
+	resources, err = session.ListResources(ctx, &mcp.ListResourcesParams{})
+	assert.NoError(t, err)
+	assert.Empty(t, resources.Resources)
+}
+
+func TestPromptToolsReachClient(t *testing.T) {
+	db := &NotifyingDB{prompts: map[string]promptsdb.Prompt{
+		"review": {Name: "review", Title: "Review", Content: "Review the code"},
+	}}
+
+	session := connectClient(t, db, make(chan struct{}, 10))
+	ctx := context.Background()
+
+	tools, err := session.ListTools(ctx, &mcp.ListToolsParams{})
+	assert.NoError(t, err)
+	assert.Len(t, tools.Tools, 7)
+
+	result, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "listPrompts", Arguments: map[string]any{}})
+	assert.NoError(t, err)
+	assert.False(t, result.IsError)
+	assert.Equal(t, map[string]any{
+		"prompts": []any{map[string]any{"name": "review", "title": "Review"}},
+		"total":   float64(1),
+	}, result.StructuredContent)
+
+	// Tools render prompts like prompts/get
+	rendered, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "renderPrompt", Arguments: map[string]any{"name": "review"}})
+	assert.NoError(t, err)
+	got, err := session.GetPrompt(ctx, &mcp.GetPromptParams{Name: "review"})
+	assert.NoError(t, err)
+	assert.Equal(t, got.Messages[0].Content, rendered.Content[0])
+
+	// The SDK validates the arguments against the input schema
+	_, err = session.CallTool(ctx, &mcp.CallToolParams{Name: "getPrompt", Arguments: map[string]any{}})
+	assert.ErrorContains(t, err, "name")
+
+	// Errors of the tools are reported in the result
+	result, err = session.CallTool(ctx, &mcp.CallToolParams{Name: "getPrompt", Arguments: map[string]any{"name": "missing"}})
+	assert.NoError(t, err)
+	assert.True(t, result.IsError)
+}
+
+func TestPartialsReachClientOnlyThroughIncludes(t *testing.T) {
+	db := &NotifyingDB{prompts: map[string]promptsdb.Prompt{
+		"review":             {Name: "review", Content: "Review. {{include \"shared/style_guide\"}}"},
+		"shared/style_guide": {Name: "shared/style_guide", Content: "Keep it short.", Partial: true},
+	}}
+
+	session := connectClient(t, db, make(chan struct{}, 10))
+	ctx := context.Background()
+
+	result, err := session.ListPrompts(ctx, &mcp.ListPromptsParams{})
+	assert.NoError(t, err)
+	assert.Len(t, result.Prompts, 1)
+	assert.Equal(t, "review", result.Prompts[0].Name)
+
+	got, err := session.GetPrompt(ctx, &mcp.GetPromptParams{Name: "review"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Review. Keep it short.", got.Messages[0].Content.(*mcp.TextContent).Text)
+
+	_, err = session.GetPrompt(ctx, &mcp.GetPromptParams{Name: "shared/style_guide"})
+	assert.Error(t, err)
+
+	resources, err := session.ListResources(ctx, &mcp.ListResourcesParams{})
+	assert.NoError(t, err)
+	assert.Len(t, resources.Resources, 1)
+	assert.Equal(t, "prompt://review", resources.Resources[0].URI)
+
+	_, err = session.ReadResource(ctx, &mcp.ReadResourceParams{URI: "prompt://shared/style_guide?rendered"})
+	assert.ErrorContains(t, err, "shared/style_guide is a partial")
+}
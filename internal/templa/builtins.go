@@ -1,8 +1,447 @@
 package templa
 
-import "time"
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	// Time zones also on hosts without a time zone database
+	_ "time/tzdata"
+)
+
+const (
+	TRUNCATION_MARKER = "..." // ends the text cut by truncate
+)
+
+// layouts are the names the time function accepts in place of a Go time layout
+var layouts = map[string]string{
+	"date":     time.DateOnly,
+	"datetime": time.DateTime,
+	"time":     time.TimeOnly,
+	"kitchen":  time.Kitchen,
+	"rfc3339":  time.RFC3339,
+	"rfc1123":  time.RFC1123,
+}
+
+// builtins holds the settings the built-in template functions depend on
+type builtins struct {
+	location *time.Location   // time zone of the time functions
+	env      []string         // environment variables the env function may read
+	now      func() time.Time // current time, replaced in tests
+}
+
+// defaultBuiltins are the built-in functions of templates processed without a Templater
+var defaultBuiltins = builtins{location: time.Local, now: time.Now}
 
 // Date returns the current date in YYYY-MM-DD format
 func Date() string {
 	return time.Now().Format("2006-01-02")
 }
+
+// LoadLocation returns the time zone with the given IANA name, e.g. Europe/Helsinki,
+// an empty name is the local time zone of the host
+func LoadLocation(name string) (*time.Location, error) {
+
+	if name == "" {
+		return time.Local, nil
+	}
+
+	return time.LoadLocation(name)
+}
+
+// funcs returns the built-in template functions
+func (b builtins) funcs() template.FuncMap {
+	return template.FuncMap{
+		// Time
+		"date":        b.date,
+		"now":         b.current,
+		"time":        b.time,
+		"weekday":     b.weekday,
+		"parseTime":   b.parseTime,
+		"addDays":     addDays,
+		"addMonths":   addMonths,
+		"addYears":    addYears,
+		"addDuration": addDuration,
+		// Strings
+		"upper":    strings.ToUpper,
+		"lower":    strings.ToLower,
+		"title":    title,
+		"trim":     strings.TrimSpace,
+		"indent":   indent,
+		"wrap":     wrap,
+		"truncate": truncate,
+		// Collections
+		"split":    split,
+		"join":     join,
+		"list":     list,
+		"default":  defaultValue,
+		"coalesce": coalesce,
+		"ternary":  ternary,
+		// Environment
+		"env":      b.environment,
+		"hostname": os.Hostname,
+		"user":     username,
+	}
+}
+
+// current returns the current time in the configured time zone
+func (b builtins) current() time.Time {
+	return b.now().In(b.location)
+}
+
+// at returns the given time, or the current time when none is given
+func (b builtins) at(times []time.Time) (time.Time, error) {
+
+	switch len(times) {
+	case 0:
+		return b.current(), nil
+	case 1:
+		return times[0], nil
+	default:
+		return time.Time{}, fmt.Errorf("expected at most one time, got %d", len(times))
+	}
+}
+
+// date returns the date of the given or the current time in YYYY-MM-DD format
+func (b builtins) date(times ...time.Time) (string, error) {
+	return b.time("date", times...)
+}
+
+// time formats the given or the current time with a Go time layout or a layout name
+func (b builtins) time(layout string, times ...time.Time) (string, error) {
+
+	t, err := b.at(times)
+	if err != nil {
+		return "", err
+	}
+
+	if named, ok := layouts[layout]; ok {
+		layout = named
+	}
+
+	return t.Format(layout), nil
+}
+
+// weekday returns the English name of the day of the week of the given or the current time
+func (b builtins) weekday(times ...time.Time) (string, error) {
+
+	t, err := b.at(times)
+	if err != nil {
+		return "", err
+	}
+
+	return t.Weekday().String(), nil
+}
+
+// parseTime reads a time in the configured time zone with a Go time layout or a layout name
+func (b builtins) parseTime(layout string, value string) (time.Time, error) {
+
+	if named, ok := layouts[layout]; ok {
+		layout = named
+	}
+
+	return time.ParseInLocation(layout, value, b.location)
+}
+
+// addDays moves the time by the given number of days, negative numbers move it back
+func addDays(days any, t time.Time) (time.Time, error) {
+
+	n, err := toInt(days)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return t.AddDate(0, 0, n), nil
+}
+
+// addMonths moves the time by the given number of months
+func addMonths(months any, t time.Time) (time.Time, error) {
+
+	n, err := toInt(months)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return t.AddDate(0, n, 0), nil
+}
+
+// addYears moves the time by the given number of years
+func addYears(years any, t time.Time) (time.Time, error) {
+
+	n, err := toInt(years)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return t.AddDate(n, 0, 0), nil
+}
+
+// addDuration moves the time by a Go duration such as 90m or -36h
+func addDuration(duration string, t time.Time) (time.Time, error) {
+
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return t.Add(d), nil
+}
+
+// title capitalizes the first letter of every word
+func title(s string) string {
+
+	runes := []rune(s)
+
+	for i, r := range runes {
+		if i == 0 || unicode.IsSpace(runes[i-1]) {
+			runes[i] = unicode.ToTitle(r)
+		}
+	}
+
+	return string(runes)
+}
+
+// indent prefixes every non-empty line with the given number of spaces
+func indent(spaces any, s string) (string, error) {
+
+	n, err := toInt(spaces)
+	if err != nil {
+		return "", err
+	}
+
+	if n < 0 {
+		return "", fmt.Errorf("negative indent %d", n)
+	}
+
+	lines := strings.Split(s, "\n")
+	padding := strings.Repeat(" ", n)
+
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = padding + line
+		}
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// wrap breaks the lines of the text between words so that they are at most the given
+// number of characters long, words longer than that are kept whole
+func wrap(width any, s string) (string, error) {
+
+	n, err := toInt(width)
+	if err != nil {
+		return "", err
+	}
+
+	if n <= 0 {
+		return "", fmt.Errorf("wrap width must be positive, got %d", n)
+	}
+
+	lines := strings.Split(s, "\n")
+
+	for i, line := range lines {
+
+		wrapped := []string{}
+		current := ""
+
+		for _, word := range strings.Fields(line) {
+			switch {
+			case current == "":
+				current = word
+			case utf8.RuneCountInString(current)+1+utf8.RuneCountInString(word) <= n:
+				current += " " + word
+			default:
+				wrapped = append(wrapped, current)
+				current = word
+			}
+		}
+
+		lines[i] = strings.Join(append(wrapped, current), "\n")
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// truncate cuts the text to at most the given number of characters, text which was cut
+// ends with the truncation marker
+func truncate(length any, s string) (string, error) {
+
+	n, err := toInt(length)
+	if err != nil {
+		return "", err
+	}
+
+	if n < 0 {
+		return "", fmt.Errorf("negative length %d", n)
+	}
+
+	runes := []rune(s)
+
+	if len(runes) <= n {
+		return s, nil
+	}
+
+	if n <= len(TRUNCATION_MARKER) {
+		return string(runes[:n]), nil
+	}
+
+	return string(runes[:n-len(TRUNCATION_MARKER)]) + TRUNCATION_MARKER, nil
+}
+
+// split splits the text at every separator and trims the white space around the parts,
+// empty text gives an empty list
+func split(separator string, s string) []string {
+
+	if strings.TrimSpace(s) == "" {
+		return []string{}
+	}
+
+	parts := strings.Split(s, separator)
+
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(part)
+	}
+
+	return parts
+}
+
+// join joins the items of a list with the separator
+func join(separator string, items any) (string, error) {
+
+	value := reflect.ValueOf(items)
+
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return "", fmt.Errorf("join expects a list, got %T", items)
+	}
+
+	parts := make([]string, value.Len())
+
+	for i := range value.Len() {
+		parts[i] = fmt.Sprint(value.Index(i).Interface())
+	}
+
+	return strings.Join(parts, separator), nil
+}
+
+// list returns its arguments as a list
+func list(items ...any) []any {
+	return items
+}
+
+// defaultValue returns the value unless it is empty, in which case the fallback is returned
+func defaultValue(fallback any, value any) any {
+
+	if empty(value) {
+		return fallback
+	}
+
+	return value
+}
+
+// coalesce returns the first value which is not empty
+func coalesce(values ...any) any {
+
+	for _, value := range values {
+		if !empty(value) {
+			return value
+		}
+	}
+
+	return nil
+}
+
+// ternary returns the first value when the condition holds and the second one otherwise
+func ternary(whenTrue any, whenFalse any, condition any) any {
+
+	if truthy(condition) {
+		return whenTrue
+	}
+
+	return whenFalse
+}
+
+// environment returns the value of an environment variable on the allowlist
+func (b builtins) environment(name string) (string, error) {
+
+	if !slices.Contains(b.env, name) {
+		return "", fmt.Errorf("environment variable %s is not allowed", name)
+	}
+
+	return os.Getenv(name), nil
+}
+
+// username returns the name of the user running prompter
+func username() (string, error) {
+
+	if current, err := user.Current(); err == nil {
+		return current.Username, nil
+	}
+
+	if name := os.Getenv("USER"); name != "" {
+		return name, nil
+	}
+
+	return "", fmt.Errorf("the current user is not known")
+}
+
+// empty reports whether the value is nil, false, zero or an empty text, list or map
+func empty(value any) bool {
+
+	if value == nil {
+		return true
+	}
+
+	v := reflect.ValueOf(value)
+
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return v.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	default:
+		return v.IsZero()
+	}
+}
+
+// truthy reports whether the condition holds, texts such as false and 0 which prompt
+// arguments use for booleans do not hold
+func truthy(condition any) bool {
+
+	if s, ok := condition.(string); ok {
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	}
+
+	return !empty(condition)
+}
+
+// toInt converts template numbers and numeric texts, such as prompt arguments, to an int
+func toInt(value any) (int, error) {
+
+	switch n := value.(type) {
+	case int:
+		return n, nil
+	case int64:
+		return int(n), nil
+	case float64:
+		return int(n), nil
+	case string:
+		i, err := strconv.Atoi(strings.TrimSpace(n))
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a whole number", n)
+		}
+		return i, nil
+	default:
+		return 0, fmt.Errorf("%v is not a whole number", value)
+	}
+}
@@ -0,0 +1,193 @@
+package templa
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fixedTemplater renders templates at 2024-02-29 23:30 UTC in the given time zone
+func fixedTemplater(t *testing.T, timezone string, env ...string) *Templater {
+	t.Helper()
+
+	templater := New(Configuration{Strictness: STRICTNESS_STRICT, Timezone: timezone, Env: env}, nil, nil)
+	templater.builtins.now = func() time.Time {
+		return time.Date(2024, time.February, 29, 23, 30, 0, 0, time.UTC)
+	}
+
+	return templater
+}
+
+func TestTimeFunctions(t *testing.T) {
+	templater := fixedTemplater(t, "Europe/Helsinki")
+
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{`{{date}}`, "2024-03-01"},
+		{`{{time "15:04"}}`, "01:30"},
+		{`{{time "datetime"}}`, "2024-03-01 01:30:00"},
+		{`{{time "rfc3339"}}`, "2024-03-01T01:30:00+02:00"},
+		{`{{weekday}}`, "Friday"},
+		{`{{now.Year}}`, "2024"},
+		{`{{now | addDays 7 | date}}`, "2024-03-08"},
+		{`{{now | addDays -1 | weekday}}`, "Thursday"},
+		{`{{now | addDays .days | date}}`, "2024-03-04"},
+		{`{{now | addMonths 1 | time "January"}}`, "April"},
+		{`{{now | addYears -1 | date}}`, "2023-03-01"},
+		{`{{now | addDuration "-2h" | time "2006-01-02 15:04"}}`, "2024-02-29 23:30"},
+		{`{{parseTime "date" .deadline | weekday}}`, "Monday"},
+		{`{{parseTime "date" .deadline | addDays 1 | time "Mon 2 Jan"}}`, "Tue 11 Jun"},
+	}
+
+	for _, test := range tests {
+		result, err := templater.Process(test.template, map[string]any{"days": "3", "deadline": "2024-06-10"})
+		if err != nil {
+			t.Errorf("%s: Process() returned error: %v", test.template, err)
+			continue
+		}
+
+		if result != test.expected {
+			t.Errorf("%s: Process() returned %q, expected %q", test.template, result, test.expected)
+		}
+	}
+
+	// The same moment is still February in UTC
+	result, _ := fixedTemplater(t, "UTC").Process(`{{date}} {{weekday}}`, nil)
+	if result != "2024-02-29 Thursday" {
+		t.Errorf("Expected the date in UTC, got %q", result)
+	}
+}
+
+func TestStringFunctions(t *testing.T) {
+	templater := fixedTemplater(t, "")
+
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{`{{upper .name}}`, "ADA LOVELACE"},
+		{`{{lower "Go Code"}}`, "go code"},
+		{`{{title "code review for äiti"}}`, "Code Review For Äiti"},
+		{`[{{trim "  padded \n"}}]`, "[padded]"},
+		{`{{indent 2 "a\n\nb"}}`, "  a\n\n  b"},
+		{`{{.code | indent "4"}}`, "    x := 1\n    y := 2"},
+		{`{{wrap 10 "the quick brown fox jumps"}}`, "the quick\nbrown fox\njumps"},
+		{`{{wrap 4 "extraordinary words"}}`, "extraordinary\nwords"},
+		{`{{truncate 8 "a rather long text"}}`, "a rat..."},
+		{`{{truncate 20 "short"}}`, "short"},
+		{`{{truncate 2 "long"}}`, "lo"},
+	}
+
+	for _, test := range tests {
+		result, err := templater.Process(test.template, map[string]any{"name": "Ada Lovelace", "code": "x := 1\ny := 2"})
+		if err != nil {
+			t.Errorf("%s: Process() returned error: %v", test.template, err)
+			continue
+		}
+
+		if result != test.expected {
+			t.Errorf("%s: Process() returned %q, expected %q", test.template, result, test.expected)
+		}
+	}
+}
+
+func TestCollectionFunctions(t *testing.T) {
+	templater := fixedTemplater(t, "")
+
+	tests := []struct {
+		template string
+		expected string
+	}{
+		{`{{.tags | split "," | join " | "}}`, "go | review | security"},
+		{`{{range split "," .tags}}[{{.}}]{{end}}`, "[go][review][security]"},
+		{`{{len (split "," .empty)}}`, "0"},
+		{`{{join ", " (list "a" 1 true)}}`, "a, 1, true"},
+		{`{{.empty | default "neutral"}}`, "neutral"},
+		{`{{.tone | default "neutral"}}`, "formal"},
+		{`{{coalesce .empty "" .tone "fallback"}}`, "formal"},
+		{`{{ternary "verbose" "terse" .verbose}}`, "terse"},
+		{`{{ternary "verbose" "terse" .tone}}`, "verbose"},
+		{`{{ternary "yes" "no" true}}`, "yes"},
+		{`{{ternary "yes" "no" .empty}}`, "no"},
+	}
+
+	args := map[string]any{"tags": "go, review ,security", "empty": "", "tone": "formal", "verbose": "false"}
+
+	for _, test := range tests {
+		result, err := templater.Process(test.template, args)
+		if err != nil {
+			t.Errorf("%s: Process() returned error: %v", test.template, err)
+			continue
+		}
+
+		if result != test.expected {
+			t.Errorf("%s: Process() returned %q, expected %q", test.template, result, test.expected)
+		}
+	}
+}
+
+func TestEnvironmentFunctions(t *testing.T) {
+	t.Setenv("PROMPTER_TEST_TEAM", "platform")
+	t.Setenv("PROMPTER_TEST_SECRET", "hunter2")
+
+	templater := fixedTemplater(t, "", "PROMPTER_TEST_TEAM")
+
+	result, err := templater.Process(`{{env "PROMPTER_TEST_TEAM"}}`, nil)
+	if err != nil || result != "platform" {
+		t.Errorf("Expected allowed environment variable, got %q and %v", result, err)
+	}
+
+	// Variables left out of the allowlist are not readable
+	result, err = templater.Process(`{{env "PROMPTER_TEST_SECRET"}}`, nil)
+	if err == nil || !strings.Contains(err.Error(), "environment variable PROMPTER_TEST_SECRET is not allowed") {
+		t.Errorf("Expected error for environment variable not on the allowlist, got %q and %v", result, err)
+	}
+
+	hostname, _ := os.Hostname()
+	result, err = templater.Process(`{{hostname}}`, nil)
+	if err != nil || result != hostname {
+		t.Errorf("Expected hostname %q, got %q and %v", hostname, result, err)
+	}
+
+	result, err = templater.Process(`{{user}}`, nil)
+	if err != nil || result == "" {
+		t.Errorf("Expected the current user, got %q and %v", result, err)
+	}
+}
+
+func TestFunctionErrors(t *testing.T) {
+	templater := fixedTemplater(t, "")
+
+	templates := []string{
+		`{{now | addDays "soon"}}`,
+		`{{now | addDuration "a while"}}`,
+		`{{parseTime "date" "tomorrow"}}`,
+		`{{wrap 0 "text"}}`,
+		`{{indent -1 "text"}}`,
+		`{{truncate -1 "text"}}`,
+		`{{join ", " "not a list"}}`,
+	}
+
+	for _, template := range templates {
+		if result, err := templater.Process(template, nil); err == nil {
+			t.Errorf("%s: expected error, got %q", template, result)
+		}
+	}
+}
+
+func TestLoadLocation(t *testing.T) {
+	if location, err := LoadLocation(""); err != nil || location != time.Local {
+		t.Errorf("Expected the local time zone for an empty name, got %v and %v", location, err)
+	}
+
+	if _, err := LoadLocation("Europe/Helsinki"); err != nil {
+		t.Errorf("Expected Europe/Helsinki to load, got %v", err)
+	}
+
+	if _, err := LoadLocation("Mars/Olympus_Mons"); err == nil {
+		t.Error("Expected error for an unknown time zone")
+	}
+}
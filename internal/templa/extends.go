@@ -0,0 +1,60 @@
+package templa
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	MAX_EXTENDS_DEPTH = 10 // longest chain of base templates
+)
+
+// layer is a template of an inheritance chain
+type layer struct {
+	name    string // name of the template, seen in the text/template error messages
+	content string
+}
+
+// bases resolves the chain of base templates starting from the named one. The layers are
+// returned outermost base first together with the defaults of the arguments the bases declare.
+func (r *render) bases(extends string) ([]layer, map[string]any, error) {
+
+	layers := []layer{}
+	defaults := map[string]any{}
+	chain := []string{}
+
+	for name := extends; name != ""; {
+
+		if r.templater.resolve == nil {
+			return nil, nil, fmt.Errorf("%s: extending templates is not available", name)
+		}
+
+		if slices.Contains(chain, name) {
+			return nil, nil, fmt.Errorf("extends cycle %s", strings.Join(append(chain, name), " -> "))
+		}
+
+		if len(chain) >= MAX_EXTENDS_DEPTH {
+			return nil, nil, fmt.Errorf("%s: templates extend each other deeper than %d", name, MAX_EXTENDS_DEPTH)
+		}
+
+		base, err := r.templater.resolve(name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		chain = append(chain, name)
+		layers = append([]layer{{name: name, content: base.Content}}, layers...)
+
+		// The nearest base declaring an argument gives its default
+		for argument, value := range base.Defaults {
+			if _, ok := defaults[argument]; !ok {
+				defaults[argument] = value
+			}
+		}
+
+		name = base.Extends
+	}
+
+	return layers, defaults, nil
+}
@@ -0,0 +1,102 @@
+package templa
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestProcessExtending(t *testing.T) {
+	resolve := includes(map[string]Include{
+		"base_review": {
+			Content:  "Review the {{.language}} code.\n{{block \"focus\" .}}Look for bugs.{{end}}\n{{block \"format\" .}}Answer in a list.{{end}}{{if .team}} For {{.team}}.{{end}}",
+			Defaults: map[string]any{"team": ""},
+		},
+		"security_review": {
+			Content: `{{define "focus"}}Look for {{block "vulnerabilities" .}}injections{{end}}.{{end}}`,
+			Extends: "base_review",
+		},
+		"shared/footer": {Content: "Thanks.", Extends: "footer_base"},
+		"footer_base":   {Content: `{{block "footer" .}}Bye.{{end}}`},
+	})
+
+	tests := []struct {
+		name     string
+		content  string
+		extends  string
+		expected string
+	}{
+		{"base blocks", "", "base_review", "Review the Go code.\nLook for bugs.\nAnswer in a list."},
+		{"override", `{{define "format"}}Answer in {{.style}}.{{end}}`, "base_review", "Review the Go code.\nLook for bugs.\nAnswer in prose."},
+		{"text outside of define is left out", `Ignored{{define "focus"}}Look for races.{{end}}`, "base_review", "Review the Go code.\nLook for races.\nAnswer in a list."},
+		{"chain", `{{define "vulnerabilities"}}leaked secrets{{end}}`, "security_review", "Review the Go code.\nLook for leaked secrets.\nAnswer in a list."},
+		{"include extending", `{{include "shared/footer"}}`, "", "Bye."},
+	}
+
+	templater := New(Configuration{Strictness: STRICTNESS_STRICT}, resolve, nil)
+
+	for _, test := range tests {
+		result, err := templater.ProcessExtending(test.content, test.extends, map[string]any{"language": "Go", "style": "prose"})
+		if err != nil {
+			t.Errorf("%s: ProcessExtending() returned error: %v", test.name, err)
+			continue
+		}
+
+		if result != test.expected {
+			t.Errorf("%s: ProcessExtending() returned %q, expected %q", test.name, result, test.expected)
+		}
+	}
+}
+
+func TestProcessExtendingErrors(t *testing.T) {
+	templates := map[string]Include{
+		"a":           {Content: "A", Extends: "b"},
+		"b":           {Content: "B", Extends: "a"},
+		"broken_base": {Content: "Fine\n{{block \"x\" .}}{{.missing}}{{end}}"},
+		"invalid":     {Content: "{{block \"x\" .}}"},
+	}
+
+	for i := 0; i <= MAX_EXTENDS_DEPTH; i++ {
+		templates[fmt.Sprintf("level%d", i)] = Include{Content: "level", Extends: fmt.Sprintf("level%d", i+1)}
+	}
+
+	tests := []struct {
+		name     string
+		content  string
+		extends  string
+		err      string
+		template string
+	}{
+		{"cycle", "", "a", "extends cycle a -> b -> a", ""},
+		{"depth", "", "level0", fmt.Sprintf("templates extend each other deeper than %d", MAX_EXTENDS_DEPTH), ""},
+		{"missing base", "", "missing", "missing: prompt not found", ""},
+		{"missing argument in base", "", "broken_base", `broken_base: line 2, column 18: <.missing>: map has no entry for key "missing"`, "broken_base"},
+		{"missing argument in override", "\n{{define \"x\"}}{{.other}}{{end}}", "broken_base", `line 2, column 17: <.other>: map has no entry for key "other"`, ""},
+		{"invalid base", "", "invalid", "invalid: line 1: ", "invalid"},
+	}
+
+	templater := New(Configuration{Strictness: STRICTNESS_STRICT}, includes(templates), nil)
+
+	for _, test := range tests {
+		result, err := templater.ProcessExtending(test.content, test.extends, nil)
+		if err == nil {
+			t.Errorf("%s: expected error, got result %q", test.name, result)
+			continue
+		}
+
+		if !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: expected error containing %q, got %q", test.name, test.err, err.Error())
+		}
+
+		var templateErr *TemplateError
+		if !errors.As(err, &templateErr) || templateErr.Template != test.template {
+			t.Errorf("%s: expected *TemplateError in template %q, got %v", test.name, test.template, err)
+		}
+	}
+
+	// Extending needs a resolver
+	if _, err := New(Configuration{}, nil, nil).ProcessExtending("", "base", nil); err == nil {
+		t.Error("Expected error extending without a resolver")
+	}
+}
@@ -0,0 +1,79 @@
+package templa
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+const (
+	MAX_INCLUDE_DEPTH = 10 // deepest nesting of included templates
+)
+
+// Include is a template included into another one with {{include "name"}} or extended by another one
+type Include struct {
+	Content  string         // template of the included prompt or partial
+	Extends  string         // name of the base template the template extends, empty when none
+	Defaults map[string]any // values of the arguments the included template declares, used when the including template does not give them
+}
+
+// Resolver looks up the template included or extended with the given name
+type Resolver func(name string) (Include, error)
+
+// include returns the include template function for the template executed with the data.
+// The included template sees the data of the including template and the arguments given
+// to include as name and value pairs, e.g. {{include "shared/tone" "tone" "formal"}}.
+func (r *render) include(data map[string]any) func(name string, pairs ...any) (string, error) {
+	return func(name string, pairs ...any) (string, error) {
+
+		if r.templater.resolve == nil {
+			return "", fmt.Errorf("%s: includes are not available", name)
+		}
+
+		if slices.Contains(r.stack, name) {
+			return "", fmt.Errorf("include cycle %s", strings.Join(append(slices.Clone(r.stack), name), " -> "))
+		}
+
+		if len(r.stack) >= MAX_INCLUDE_DEPTH {
+			return "", fmt.Errorf("%s: includes are nested deeper than %d", name, MAX_INCLUDE_DEPTH)
+		}
+
+		if len(pairs)%2 != 0 {
+			return "", fmt.Errorf("%s: include arguments must be given as name and value pairs", name)
+		}
+
+		included, err := r.templater.resolve(name)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", name, err)
+		}
+
+		// Arguments of the include override the data of the including template, which overrides the defaults
+		arguments := map[string]any{}
+		maps.Copy(arguments, included.Defaults)
+		maps.Copy(arguments, data)
+
+		for i := 0; i < len(pairs); i += 2 {
+
+			key, ok := pairs[i].(string)
+			if !ok {
+				return "", fmt.Errorf("%s: include argument name %v is not a string", name, pairs[i])
+			}
+
+			arguments[key] = pairs[i+1]
+		}
+
+		nested := &render{
+			templater:  r.templater,
+			missingKey: r.missingKey,
+			stack:      append(slices.Clone(r.stack), name),
+		}
+
+		result, err := nested.execute(included.Content, included.Extends, arguments)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", name, toTemplateError(err))
+		}
+
+		return result, nil
+	}
+}
@@ -0,0 +1,119 @@
+package templa
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// includes resolves the templates of the map
+func includes(templates map[string]Include) Resolver {
+	return func(name string) (Include, error) {
+		included, ok := templates[name]
+		if !ok {
+			return Include{}, fmt.Errorf("prompt not found")
+		}
+		return included, nil
+	}
+}
+
+func TestInclude(t *testing.T) {
+	resolve := includes(map[string]Include{
+		"shared/style_guide": {Content: "Write in a {{.tone}} tone{{if .audience}} for {{.audience}}{{end}}.", Defaults: map[string]any{"tone": "neutral", "audience": ""}},
+		"shared/format":      {Content: "Answer in {{.format}}. {{include \"shared/style_guide\"}}"},
+	})
+
+	tests := []struct {
+		name     string
+		content  string
+		args     map[string]any
+		expected string
+	}{
+		{"defaults", `Review. {{include "shared/style_guide"}}`, nil, "Review. Write in a neutral tone."},
+		{"arguments of the including template", `{{include "shared/style_guide"}}`, map[string]any{"tone": "formal"}, "Write in a formal tone."},
+		{"arguments of the include", `{{include "shared/style_guide" "tone" "friendly" "audience" .who}}`, map[string]any{"tone": "formal", "who": "students"}, "Write in a friendly tone for students."},
+		{"nested", `{{include "shared/format" "format" "markdown"}}`, nil, "Answer in markdown. Write in a neutral tone."},
+	}
+
+	templater := New(Configuration{Strictness: STRICTNESS_STRICT}, resolve, nil)
+
+	for _, test := range tests {
+		result, err := templater.Process(test.content, test.args)
+		if err != nil {
+			t.Errorf("%s: Process() returned error: %v", test.name, err)
+			continue
+		}
+
+		if result != test.expected {
+			t.Errorf("%s: Process() returned %q, expected %q", test.name, result, test.expected)
+		}
+	}
+}
+
+func TestIncludeErrors(t *testing.T) {
+	templates := map[string]Include{
+		"a":       {Content: `A {{include "b"}}`},
+		"b":       {Content: `B {{include "a"}}`},
+		"self":    {Content: `{{include "self"}}`},
+		"broken":  {Content: "Fine\n  {{.missing}}"},
+		"invalid": {Content: "{{.name"},
+	}
+
+	// Every level includes the next one, deeper than allowed
+	for i := 0; i <= MAX_INCLUDE_DEPTH; i++ {
+		templates[fmt.Sprintf("level%d", i)] = Include{Content: fmt.Sprintf(`{{include "level%d"}}`, i+1)}
+	}
+	templates[fmt.Sprintf("level%d", MAX_INCLUDE_DEPTH+1)] = Include{Content: "bottom"}
+
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"cycle", `{{include "a"}}`, "include cycle a -> b -> a"},
+		{"self", `{{include "self"}}`, "include cycle self -> self"},
+		{"depth", `{{include "level0"}}`, fmt.Sprintf("includes are nested deeper than %d", MAX_INCLUDE_DEPTH)},
+		{"not found", `{{include "missing"}}`, "missing: prompt not found"},
+		{"odd arguments", `{{include "broken" "tone"}}`, "name and value pairs"},
+		{"missing argument", "Line\n{{include \"broken\"}}", `broken: line 2, column 5: <.missing>: map has no entry for key "missing"`},
+		{"invalid include", `{{include "invalid"}}`, "invalid: line 1: "},
+	}
+
+	templater := New(Configuration{Strictness: STRICTNESS_STRICT}, includes(templates), nil)
+
+	for _, test := range tests {
+		result, err := templater.Process(test.content, nil)
+		if err == nil {
+			t.Errorf("%s: expected error, got result %q", test.name, result)
+			continue
+		}
+
+		if !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: expected error containing %q, got %q", test.name, test.err, err.Error())
+		}
+
+		var templateErr *TemplateError
+		if !errors.As(err, &templateErr) || templateErr.Line == 0 {
+			t.Errorf("%s: expected *TemplateError with the line of the include, got %v", test.name, err)
+		}
+	}
+
+	// The missing argument of the included template is reported in warn mode
+	warned := ""
+	templater = New(Configuration{Strictness: STRICTNESS_WARN}, includes(templates), func(err *TemplateError) {
+		warned = err.Argument
+	})
+
+	result, err := templater.Process(`{{include "broken"}}`, nil)
+	if err != nil || result != "Fine\n  <no value>" || warned != "missing" {
+		t.Errorf("warn: got %q, %v and warning about %q", result, err, warned)
+	}
+}
+
+func TestIncludeWithoutResolver(t *testing.T) {
+	_, err := Process(`{{include "shared/style_guide"}}`, nil)
+	if err == nil || !strings.Contains(err.Error(), "includes are not available") {
+		t.Errorf("Expected error about includes not being available, got %v", err)
+	}
+}
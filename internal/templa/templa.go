@@ -1,36 +1,273 @@
 package templa
 
 import (
+	"fmt"
+	"maps"
+	"regexp"
+	"strconv"
 	"strings"
 	"text/template"
+	"time"
 )
 
+// Strictness levels deciding how templates referring to arguments that were not given are rendered
+const (
+	STRICTNESS_LENIENT = "lenient" // missing arguments render as <no value>
+	STRICTNESS_WARN    = "warn"    // missing arguments render as <no value> and are reported to the warning function
+	STRICTNESS_STRICT  = "strict"  // missing arguments fail the rendering
+
+	DEFAULT_STRICTNESS = STRICTNESS_WARN // strictness used when not configured
+
+	TEMPLATE_NAME = "prompt" // name given to the parsed templates, seen in the text/template error messages
+)
+
+var (
+	// errorPosition picks the template name, the line, the optional column and the message from text/template errors
+	errorPosition = regexp.MustCompile(`(?s)^template: (.+?):(\d+)(?::(\d+))?: (.*)$`)
+	// executing picks the name of the executed template from the start of text/template execution errors
+	executing = regexp.MustCompile(`^executing "[^"]*" at `)
+	// missingKey picks the argument name from errors of missingkey=error
+	missingKey = regexp.MustCompile(`map has no entry for key "(.*)"$`)
+)
+
+// Configuration holds the settings of prompt templating
+type Configuration struct {
+	Strictness string   `yaml:"strictness" koanf:"strictness"` // one of lenient, warn or strict
+	Timezone   string   `yaml:"timezone" koanf:"timezone"`     // IANA time zone of the time functions, e.g. Europe/Helsinki, the host time zone when empty
+	Env        []string `yaml:"env" koanf:"env"`               // environment variables templates may read with the env function
+}
+
 // TemplateData holds the data for template execution
 type TemplateData struct {
 	// Add fields as needed for template data
 }
 
-// processTemplate processes template content with arguments
-func Process(content string, args map[string]string) string {
-	// Create template with built-in functions
-	tmpl := createTemplate("prompt")
+// TemplateError tells where in the template parsing or executing it failed
+type TemplateError struct {
+	Template string // name of the extended template the error is in, empty for the processed template
+	Line     int    // line of the template starting from 1, 0 when unknown
+	Column   int    // column of the template starting from 1, 0 when unknown
+	Argument string // argument the template refers to which was not given
+	Message  string // description of the failure without the position
+	Err      error  // error returned by text/template
+}
+
+func (e *TemplateError) Error() string {
+
+	prefix := ""
+
+	if e.Template != "" {
+		prefix = e.Template + ": "
+	}
+
+	switch {
+	case e.Line > 0 && e.Column > 0:
+		return fmt.Sprintf("%sline %d, column %d: %s", prefix, e.Line, e.Column, e.Message)
+	case e.Line > 0:
+		return fmt.Sprintf("%sline %d: %s", prefix, e.Line, e.Message)
+	default:
+		return prefix + e.Message
+	}
+}
+
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// Templater renders prompt templates with the configured strictness
+type Templater struct {
+	strictness string
+	builtins   builtins
+	resolve    Resolver
+	warn       func(err *TemplateError)
+}
+
+// render holds the state of rendering a single template and the templates it includes
+type render struct {
+	templater  *Templater
+	missingKey string   // missingkey option of text/template
+	stack      []string // names of the templates being included, outermost first
+}
+
+// ValidStrictness reports whether the strictness is one of the known levels, empty
+// strictness is valid and stands for the default
+func ValidStrictness(strictness string) bool {
+
+	switch strictness {
+	case "", STRICTNESS_LENIENT, STRICTNESS_WARN, STRICTNESS_STRICT:
+		return true
+	default:
+		return false
+	}
+}
+
+// New creates a Templater, resolve looks up the templates included with {{include}} and
+// warn is called with the arguments missing from templates rendered in warn mode, both may be nil
+func New(config Configuration, resolve Resolver, warn func(err *TemplateError)) *Templater {
+
+	strictness := config.Strictness
 
-	// Parse the template
-	parsedTemplate, err := tmpl.Parse(content)
+	if !ValidStrictness(strictness) || strictness == "" {
+		strictness = DEFAULT_STRICTNESS
+	}
+
+	// The configuration is validated when loaded, an unknown time zone falls back to the host one
+	location, err := LoadLocation(config.Timezone)
 	if err != nil {
-		// If template parsing fails, return original content
-		return content
+		location = time.Local
+	}
+
+	return &Templater{
+		strictness: strictness,
+		builtins: builtins{
+			location: location,
+			env:      config.Env,
+			now:      time.Now,
+		},
+		resolve: resolve,
+		warn:    warn,
+	}
+}
+
+// Process processes template content with text arguments leniently
+func Process(content string, args map[string]string) (string, error) {
+	return New(Configuration{Strictness: STRICTNESS_LENIENT}, nil, nil).Process(content, convertArgsToInterface(args))
+}
+
+// Process processes template content with arguments, the arguments may be of any type such
+// as booleans for {{if}} and lists for {{range}}. Templates which fail to parse or execute
+// return a *TemplateError, as do missing arguments in strict mode.
+func (t *Templater) Process(content string, args map[string]any) (string, error) {
+	return t.ProcessExtending(content, "", args)
+}
+
+// ProcessExtending processes template content extending the named base template, see
+// Process. The outermost base template is rendered with the blocks the content and the
+// bases between redefine, the content outside of the {{define}} actions is not rendered.
+func (t *Templater) ProcessExtending(content string, extends string, args map[string]any) (string, error) {
+
+	// Missing arguments are looked up from an empty map
+	data := args
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+
+	if t.strictness == STRICTNESS_LENIENT {
+		return t.execute(content, extends, data, "default")
+	}
+
+	result, err := t.execute(content, extends, data, "error")
+	if err == nil || t.strictness == STRICTNESS_STRICT {
+		return result, err
 	}
 
+	templateErr, ok := err.(*TemplateError)
+	if !ok || templateErr.Argument == "" {
+		return result, err
+	}
+
+	// Warn about the missing argument and render it like lenient mode does
+	if t.warn != nil {
+		t.warn(templateErr)
+	}
+
+	return t.execute(content, extends, data, "default")
+}
+
+// execute renders the template with the given missingkey option
+func (t *Templater) execute(content string, extends string, data map[string]any, missingKey string) (string, error) {
+
+	r := &render{
+		templater:  t,
+		missingKey: missingKey,
+	}
+
+	result, err := r.execute(content, extends, data)
+	if err != nil {
+		return "", toTemplateError(err)
+	}
+
+	return result, nil
+}
+
+// execute parses and executes the template with the templates it extends, the returned
+// errors are the ones of text/template
+func (r *render) execute(content string, extends string, data map[string]any) (string, error) {
+
+	layers, defaults, err := r.bases(extends)
+	if err != nil {
+		return "", err
+	}
+
+	// The arguments the bases declare render empty unless given
+	if len(defaults) > 0 {
+		arguments := maps.Clone(defaults)
+		maps.Copy(arguments, data)
+		data = arguments
+	}
+
+	layers = append(layers, layer{name: TEMPLATE_NAME, content: content})
+
+	// Create template with built-in functions, the outermost base is the one executed
+	root := createTemplate(layers[0].name).Funcs(r.templater.builtins.funcs()).Option("missingkey=" + r.missingKey)
+
+	// Parse the templates from the outermost base in, the blocks defined later replace the earlier ones
+	for i, layer := range layers {
+
+		tmpl := root
+		if i > 0 {
+			tmpl = root.New(layer.name)
+		}
+
+		if _, err := tmpl.Parse(layer.content); err != nil {
+			return "", err
+		}
+	}
+
+	// Includes see the data of this template
+	root.Funcs(template.FuncMap{
+		"include": r.include(data),
+	})
+
 	// Execute template with arguments
 	var result strings.Builder
-	err = parsedTemplate.Execute(&result, convertArgsToInterface(args))
+	err = root.Execute(&result, data)
 	if err != nil {
-		// If template execution fails, return original content
-		return content
+		return "", err
 	}
 
-	return result.String()
+	return result.String(), nil
+}
+
+// toTemplateError picks the position from the text/template error
+func toTemplateError(err error) *TemplateError {
+
+	templateErr := &TemplateError{Message: err.Error(), Err: err}
+
+	match := errorPosition.FindStringSubmatch(err.Error())
+	if match == nil {
+		return templateErr
+	}
+
+	if match[1] != TEMPLATE_NAME {
+		templateErr.Template = match[1]
+	}
+
+	templateErr.Line, _ = strconv.Atoi(match[2])
+	templateErr.Message = executing.ReplaceAllString(match[4], "")
+	templateErr.Message = strings.Replace(templateErr.Message, "error calling include: ", "", 1)
+
+	// text/template counts the columns from 0
+	if match[3] != "" {
+		column, _ := strconv.Atoi(match[3])
+		templateErr.Column = column + 1
+	}
+
+	if missing := missingKey.FindStringSubmatch(templateErr.Message); missing != nil {
+		templateErr.Argument = missing[1]
+	}
+
+	return templateErr
 }
 
 // convertArgsToInterface converts string arguments to interface{}
@@ -50,8 +287,14 @@ func convertArgsToInterface(args map[string]string) map[string]interface{} {
 func createTemplate(name string) *template.Template {
 	t := template.New(name)
 	// Register built-in functions
+	t = t.Funcs(defaultBuiltins.funcs())
 	t = t.Funcs(template.FuncMap{
-		"date": Date,
+		"include": noInclude,
 	})
 	return t
 }
+
+// noInclude stands for the include function until the template is executed
+func noInclude(name string, pairs ...any) (string, error) {
+	return "", fmt.Errorf("%s: includes are not available", name)
+}
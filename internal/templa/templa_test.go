@@ -46,3 +46,147 @@ func TestCreateTemplate(t *testing.T) {
 		t.Error("Template execution produced empty result")
 	}
 }
+
+func TestProcess(t *testing.T) {
+	result, err := Process("Hello {{.name}}", map[string]string{"name": "World"})
+	if err != nil {
+		t.Fatalf("Process() returned error: %v", err)
+	}
+
+	if result != "Hello World" {
+		t.Errorf("Process() returned %q, expected %q", result, "Hello World")
+	}
+
+	// Missing arguments render as <no value> in lenient mode
+	result, err = Process("Hello {{.name}}", nil)
+	if err != nil {
+		t.Fatalf("Process() returned error for missing argument: %v", err)
+	}
+
+	if result != "Hello <no value>" {
+		t.Errorf("Process() returned %q, expected %q", result, "Hello <no value>")
+	}
+}
+
+func TestProcessErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		line     int
+		column   int
+		argument string
+	}{
+		{"unclosed action", "Hello\n{{.name", 2, 0, ""},
+		{"unknown function", "{{unknown}}", 1, 0, ""},
+		{"failing function", "Line\nLine\n  {{index .name 3}}", 3, 5, ""},
+		{"missing argument", "Hello\n  {{.missing}}", 2, 5, "missing"},
+	}
+
+	templater := New(Configuration{Strictness: STRICTNESS_STRICT}, nil, nil)
+
+	for _, test := range tests {
+		result, err := templater.Process(test.content, map[string]any{"name": "ab"})
+		if err == nil {
+			t.Errorf("%s: expected error, got result %q", test.name, result)
+			continue
+		}
+
+		templateErr, ok := err.(*TemplateError)
+		if !ok {
+			t.Errorf("%s: expected *TemplateError, got %T", test.name, err)
+			continue
+		}
+
+		if templateErr.Line != test.line || templateErr.Column != test.column {
+			t.Errorf("%s: expected line %d column %d, got line %d column %d", test.name, test.line, test.column, templateErr.Line, templateErr.Column)
+		}
+
+		if templateErr.Argument != test.argument {
+			t.Errorf("%s: expected missing argument %q, got %q", test.name, test.argument, templateErr.Argument)
+		}
+
+		if strings.HasPrefix(templateErr.Error(), "template:") {
+			t.Errorf("%s: expected the position without the template name, got %q", test.name, templateErr.Error())
+		}
+	}
+}
+
+func TestProcessTypedArguments(t *testing.T) {
+	templater := New(Configuration{Strictness: STRICTNESS_STRICT}, nil, nil)
+
+	content := `{{if .verbose}}Explain every finding. {{end}}{{range .files}}[{{.}}]{{end}} at most {{.limit}}{{with .options}} in {{.format}}{{end}}`
+	args := map[string]any{
+		"verbose": false,
+		"files":   []any{"main.go", "go.mod"},
+		"limit":   3,
+		"options": map[string]any{"format": "markdown"},
+	}
+
+	result, err := templater.Process(content, args)
+	if err != nil {
+		t.Fatalf("Process() returned error: %v", err)
+	}
+
+	expected := "[main.go][go.mod] at most 3 in markdown"
+	if result != expected {
+		t.Errorf("Process() returned %q, expected %q", result, expected)
+	}
+}
+
+func TestProcessStrictness(t *testing.T) {
+	content := "Hello {{.name}}, {{.missing}}"
+	args := map[string]any{"name": "World"}
+
+	// Lenient renders missing arguments without warnings
+	warnings := []*TemplateError{}
+	warn := func(err *TemplateError) {
+		warnings = append(warnings, err)
+	}
+
+	result, err := New(Configuration{Strictness: STRICTNESS_LENIENT}, nil, warn).Process(content, args)
+	if err != nil || result != "Hello World, <no value>" || len(warnings) != 0 {
+		t.Errorf("lenient: got %q, %v and %d warnings", result, err, len(warnings))
+	}
+
+	// Warn renders missing arguments and reports them
+	result, err = New(Configuration{Strictness: STRICTNESS_WARN}, nil, warn).Process(content, args)
+	if err != nil || result != "Hello World, <no value>" {
+		t.Errorf("warn: got %q and %v", result, err)
+	}
+
+	if len(warnings) != 1 || warnings[0].Argument != "missing" {
+		t.Errorf("warn: expected a warning about missing, got %v", warnings)
+	}
+
+	// Warn still fails on broken templates
+	_, err = New(Configuration{Strictness: STRICTNESS_WARN}, nil, warn).Process("{{.name", args)
+	if err == nil {
+		t.Error("warn: expected error for broken template")
+	}
+
+	// Strict fails on missing arguments
+	result, err = New(Configuration{Strictness: STRICTNESS_STRICT}, nil, warn).Process(content, args)
+	if err == nil {
+		t.Errorf("strict: expected error, got %q", result)
+	}
+
+	// Given arguments render in every mode
+	for _, strictness := range []string{STRICTNESS_LENIENT, STRICTNESS_WARN, STRICTNESS_STRICT} {
+		result, err = New(Configuration{Strictness: strictness}, nil, nil).Process("Hello {{.name}}", args)
+		if err != nil || result != "Hello World" {
+			t.Errorf("%s: got %q and %v", strictness, result, err)
+		}
+	}
+}
+
+func TestValidStrictness(t *testing.T) {
+	for _, strictness := range []string{"", STRICTNESS_LENIENT, STRICTNESS_WARN, STRICTNESS_STRICT} {
+		if !ValidStrictness(strictness) {
+			t.Errorf("ValidStrictness(%q) returned false", strictness)
+		}
+	}
+
+	if ValidStrictness("pedantic") {
+		t.Error("ValidStrictness(\"pedantic\") returned true")
+	}
+}
@@ -0,0 +1,395 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/hkionline/prompter/internal/plog"
+	"github.com/hkionline/prompter/internal/promptsdb"
+	"github.com/modelcontextprotocol/go-sdk/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+const (
+	DEFAULT_LIST_LIMIT   = 50 // number of prompts listed by listPrompts when no limit is given
+	DEFAULT_SEARCH_LIMIT = 10 // number of prompts returned by searchPrompts when no limit is given
+)
+
+// Relevance of the prompt fields matching a search, matches in the name weigh the most
+const (
+	SCORE_NAME        = 8
+	SCORE_TITLE       = 4
+	SCORE_TAG         = 3
+	SCORE_DESCRIPTION = 2
+	SCORE_CONTENT     = 1
+)
+
+// UpdatePromptTool creates the tool definition for changing prompts
+func (h *ToolHandler) UpdatePromptTool() *mcp.Tool {
+	return &mcp.Tool{
+		Name:        UPDATE_PROMPT,
+		Title:       "Update prompt",
+		Description: "Change an existing prompt. Only the given fields are changed, the others keep their current values.",
+		InputSchema: &jsonschema.Schema{
+			Type: "object",
+			Properties: map[string]*jsonschema.Schema{
+				"name":        stringSchema("Name of the prompt to change."),
+				"title":       stringSchema("New human readable display name for the prompt."),
+				"description": stringSchema("New human readable explanation what the prompt is for."),
+				"content":     stringSchema("New full content of the prompt."),
+				"arguments":   argumentsSchema("New arguments of the prompt, replacing the current ones."),
+				"tags":        stringsSchema("New tags of the prompt, replacing the current ones."),
+				"extends":     stringSchema("New base prompt the content extends, an empty name stops extending."),
+				"partial":     {Type: "boolean", Description: "Whether the prompt is a partial only included in other prompts with {{include \"name\"}}."},
+				"revision":    stringSchema("Revision of the prompt the change is based on as returned by getPrompt. The update fails if the prompt was changed since."),
+			},
+			Required: []string{"name"},
+		},
+		OutputSchema: promptOutputSchema(),
+	}
+}
+
+// DeletePromptTool creates the tool definition for removing prompts
+func (h *ToolHandler) DeletePromptTool() *mcp.Tool {
+	return &mcp.Tool{
+		Name:        DELETE_PROMPT,
+		Title:       "Delete prompt",
+		Description: "Remove a prompt from the prompt library",
+		InputSchema: &jsonschema.Schema{
+			Type: "object",
+			Properties: map[string]*jsonschema.Schema{
+				"name": stringSchema("Name of the prompt to remove."),
+			},
+			Required: []string{"name"},
+		},
+		OutputSchema: &jsonschema.Schema{
+			Type: "object",
+			Properties: map[string]*jsonschema.Schema{
+				"name": stringSchema("Name of the removed prompt."),
+			},
+			Required: []string{"name"},
+		},
+	}
+}
+
+// GetPromptTool creates the tool definition for reading a prompt
+func (h *ToolHandler) GetPromptTool() *mcp.Tool {
+	return &mcp.Tool{
+		Name:        GET_PROMPT,
+		Title:       "Get prompt",
+		Description: "Read a prompt with its template, arguments and revision",
+		InputSchema: &jsonschema.Schema{
+			Type: "object",
+			Properties: map[string]*jsonschema.Schema{
+				"name": stringSchema("Name of the prompt to read."),
+			},
+			Required: []string{"name"},
+		},
+		OutputSchema: promptOutputSchema(),
+	}
+}
+
+// ListPromptsTool creates the tool definition for listing prompts
+func (h *ToolHandler) ListPromptsTool() *mcp.Tool {
+	return &mcp.Tool{
+		Name:        LIST_PROMPTS,
+		Title:       "List prompts",
+		Description: "List the prompts in the prompt library sorted by name, optionally filtered by name, tag or namespace",
+		InputSchema: &jsonschema.Schema{
+			Type: "object",
+			Properties: map[string]*jsonschema.Schema{
+				"nameStartsWith": stringSchema("Only prompts with a name starting with the text."),
+				"nameContains":   stringSchema("Only prompts with a name containing the text."),
+				"tag":            stringSchema("Only prompts with the tag."),
+				"namespace":      stringSchema("Only prompts in the namespace, e.g. review for review/security_audit, or in namespaces nested in it."),
+				"offset":         {Type: "integer", Description: "Number of matching prompts to skip.", Minimum: jsonschema.Ptr(0.0)},
+				"limit":          {Type: "integer", Description: fmt.Sprintf("Largest number of prompts to return, %d by default.", DEFAULT_LIST_LIMIT), Minimum: jsonschema.Ptr(1.0)},
+			},
+		},
+		OutputSchema: promptsOutputSchema(),
+	}
+}
+
+// SearchPromptsTool creates the tool definition for searching prompts
+func (h *ToolHandler) SearchPromptsTool() *mcp.Tool {
+	return &mcp.Tool{
+		Name:        SEARCH_PROMPTS,
+		Title:       "Search prompts",
+		Description: "Search the prompt library for prompts matching all the given words in their name, title, tags, description or content, the best matches first",
+		InputSchema: &jsonschema.Schema{
+			Type: "object",
+			Properties: map[string]*jsonschema.Schema{
+				"query": stringSchema("Words to search for, case is ignored."),
+				"limit": {Type: "integer", Description: fmt.Sprintf("Largest number of prompts to return, %d by default.", DEFAULT_SEARCH_LIMIT), Minimum: jsonschema.Ptr(1.0)},
+			},
+			Required: []string{"query"},
+		},
+		OutputSchema: promptsOutputSchema(),
+	}
+}
+
+// handleUpdatePrompt handles the updatePrompt tool call. The prompt is read and the given
+// fields are changed, so metadata the tool does not know about is kept as it is.
+func (h *ToolHandler) handleUpdatePrompt(ctx context.Context, req *mcp.CallToolParamsFor[map[string]any]) (*mcp.CallToolResult, error) {
+
+	var input struct {
+		Name        string                `json:"name"`
+		Title       *string               `json:"title"`
+		Description *string               `json:"description"`
+		Content     *string               `json:"content"`
+		Arguments   *[]promptsdb.Argument `json:"arguments"`
+		Tags        *[]string             `json:"tags"`
+		Extends     *string               `json:"extends"`
+		Partial     *bool                 `json:"partial"`
+		Revision    string                `json:"revision"`
+	}
+
+	if err := decodeArguments(req.Arguments, &input); err != nil {
+		h.logger.Write(plog.SERVER, "Invalid arguments for updatePrompt: %s", err.Error())
+		return nil, err
+	}
+
+	prompt, err := h.db.Read(input.Name)
+	if err != nil {
+		h.logger.Write(plog.SERVER, "Failed to read prompt: %s", err.Error())
+		return nil, fmt.Errorf("failed to update prompt: %w", err)
+	}
+
+	if input.Title != nil {
+		prompt.Title = *input.Title
+	}
+	if input.Description != nil {
+		prompt.Description = *input.Description
+	}
+	if input.Content != nil {
+		prompt.Content = *input.Content
+	}
+	if input.Arguments != nil {
+		prompt.Arguments = *input.Arguments
+	}
+	if input.Tags != nil {
+		prompt.Tags = *input.Tags
+	}
+	if input.Extends != nil {
+		prompt.Extends = *input.Extends
+	}
+	if input.Partial != nil {
+		prompt.Partial = *input.Partial
+	}
+
+	// Without a revision the update is based on the prompt just read
+	if input.Revision != "" {
+		prompt.Revision = input.Revision
+	}
+
+	if err = h.db.Update(prompt); err != nil {
+		h.logger.Write(plog.SERVER, "Failed to update prompt: %s", err.Error())
+		return nil, fmt.Errorf("failed to update prompt: %w", err)
+	}
+
+	// The provider gives the stored prompt a new revision
+	updated, err := h.db.Read(prompt.Name)
+	if err != nil {
+		h.logger.Write(plog.SERVER, "Failed to read updated prompt: %s", err.Error())
+		return nil, fmt.Errorf("failed to read updated prompt: %w", err)
+	}
+
+	return structuredResult(toPromptOutput(updated))
+}
+
+// handleDeletePrompt handles the deletePrompt tool call
+func (h *ToolHandler) handleDeletePrompt(ctx context.Context, req *mcp.CallToolParamsFor[map[string]any]) (*mcp.CallToolResult, error) {
+
+	var input struct {
+		Name string `json:"name"`
+	}
+
+	if err := decodeArguments(req.Arguments, &input); err != nil {
+		h.logger.Write(plog.SERVER, "Invalid arguments for deletePrompt: %s", err.Error())
+		return nil, err
+	}
+
+	if err := h.db.Delete(input.Name); err != nil {
+		h.logger.Write(plog.SERVER, "Failed to delete prompt: %s", err.Error())
+		return nil, fmt.Errorf("failed to delete prompt: %w", err)
+	}
+
+	return structuredResult(map[string]string{"name": input.Name})
+}
+
+// handleGetPrompt handles the getPrompt tool call
+func (h *ToolHandler) handleGetPrompt(ctx context.Context, req *mcp.CallToolParamsFor[map[string]any]) (*mcp.CallToolResult, error) {
+
+	var input struct {
+		Name string `json:"name"`
+	}
+
+	if err := decodeArguments(req.Arguments, &input); err != nil {
+		h.logger.Write(plog.SERVER, "Invalid arguments for getPrompt: %s", err.Error())
+		return nil, err
+	}
+
+	prompt, err := h.db.Read(input.Name)
+	if err != nil {
+		h.logger.Write(plog.SERVER, "Failed to read prompt: %s", err.Error())
+		return nil, fmt.Errorf("failed to get prompt: %w", err)
+	}
+
+	return structuredResult(toPromptOutput(prompt))
+}
+
+// handleListPrompts handles the listPrompts tool call
+func (h *ToolHandler) handleListPrompts(ctx context.Context, req *mcp.CallToolParamsFor[map[string]any]) (*mcp.CallToolResult, error) {
+
+	var input struct {
+		NameStartsWith string `json:"nameStartsWith"`
+		NameContains   string `json:"nameContains"`
+		Tag            string `json:"tag"`
+		Namespace      string `json:"namespace"`
+		Offset         int    `json:"offset"`
+		Limit          int    `json:"limit"`
+	}
+
+	if err := decodeArguments(req.Arguments, &input); err != nil {
+		h.logger.Write(plog.SERVER, "Invalid arguments for listPrompts: %s", err.Error())
+		return nil, err
+	}
+
+	if input.Limit <= 0 {
+		input.Limit = DEFAULT_LIST_LIMIT
+	}
+
+	offset := max(input.Offset, 0)
+
+	prompts, total, err := h.db.List(promptsdb.PromptQuery{
+		NameStartsWith: input.NameStartsWith,
+		NameContains:   input.NameContains,
+		Tag:            input.Tag,
+		Namespace:      input.Namespace,
+		IndexFrom:      offset,
+		IndexTo:        offset + input.Limit,
+	})
+	if err != nil {
+		h.logger.Write(plog.SERVER, "Error listing prompts: %s", err.Error())
+		return nil, fmt.Errorf("failed to list prompts: %w", err)
+	}
+
+	output := PromptsOutput{
+		Prompts: []PromptSummary{},
+		Total:   total,
+	}
+
+	for _, prompt := range prompts {
+		output.Prompts = append(output.Prompts, toPromptSummary(prompt))
+	}
+
+	if next := offset + len(prompts); len(prompts) > 0 && next < total {
+		output.NextOffset = next
+	}
+
+	return structuredResult(output)
+}
+
+// handleSearchPrompts handles the searchPrompts tool call
+func (h *ToolHandler) handleSearchPrompts(ctx context.Context, req *mcp.CallToolParamsFor[map[string]any]) (*mcp.CallToolResult, error) {
+
+	var input struct {
+		Query string `json:"query"`
+		Limit int    `json:"limit"`
+	}
+
+	if err := decodeArguments(req.Arguments, &input); err != nil {
+		h.logger.Write(plog.SERVER, "Invalid arguments for searchPrompts: %s", err.Error())
+		return nil, err
+	}
+
+	words := strings.Fields(strings.ToLower(input.Query))
+
+	if len(words) == 0 {
+		h.logger.Write(plog.SERVER, "Empty search query")
+		return nil, fmt.Errorf("missing search query")
+	}
+
+	if input.Limit <= 0 {
+		input.Limit = DEFAULT_SEARCH_LIMIT
+	}
+
+	prompts, _, err := h.db.List(promptsdb.PromptQuery{All: true})
+	if err != nil {
+		h.logger.Write(plog.SERVER, "Error listing prompts: %s", err.Error())
+		return nil, fmt.Errorf("failed to search prompts: %w", err)
+	}
+
+	type match struct {
+		prompt promptsdb.Prompt
+		score  int
+	}
+
+	matches := []match{}
+
+	for _, prompt := range prompts {
+		if score := searchScore(prompt, words); score > 0 {
+			matches = append(matches, match{prompt, score})
+		}
+	}
+
+	// The prompts come sorted by name, a stable sort keeps the order among equal scores
+	slices.SortStableFunc(matches, func(a, b match) int {
+		return b.score - a.score
+	})
+
+	output := PromptsOutput{
+		Prompts: []PromptSummary{},
+		Total:   len(matches),
+	}
+
+	for _, m := range matches[:min(len(matches), input.Limit)] {
+		output.Prompts = append(output.Prompts, toPromptSummary(m.prompt))
+	}
+
+	return structuredResult(output)
+}
+
+// searchScore rates how well the prompt matches the lower case search words,
+// zero means that some of the words are not found in the prompt at all
+func searchScore(prompt promptsdb.Prompt, words []string) int {
+
+	name := strings.ToLower(prompt.Name)
+	title := strings.ToLower(prompt.Title)
+	description := strings.ToLower(prompt.Description)
+	content := strings.ToLower(prompt.Content)
+	tags := strings.ToLower(strings.Join(prompt.Tags, " "))
+
+	total := 0
+
+	for _, word := range words {
+
+		score := 0
+
+		if strings.Contains(name, word) {
+			score += SCORE_NAME
+		}
+		if strings.Contains(title, word) {
+			score += SCORE_TITLE
+		}
+		if strings.Contains(tags, word) {
+			score += SCORE_TAG
+		}
+		if strings.Contains(description, word) {
+			score += SCORE_DESCRIPTION
+		}
+		if strings.Contains(content, word) {
+			score += SCORE_CONTENT
+		}
+
+		if score == 0 {
+			return 0
+		}
+
+		total += score
+	}
+
+	return total
+}
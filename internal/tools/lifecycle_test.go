@@ -0,0 +1,284 @@
+package tools
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/hkionline/prompter/internal/plog"
+	"github.com/hkionline/prompter/internal/promptsdb"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+// MemoryDB is an in-memory promptsdb.Provider giving the prompts revisions like the real providers
+type MemoryDB struct {
+	mu      sync.Mutex
+	prompts map[string]promptsdb.Prompt
+}
+
+func NewMemoryDB(prompts ...promptsdb.Prompt) *MemoryDB {
+	db := &MemoryDB{prompts: map[string]promptsdb.Prompt{}}
+	for _, prompt := range prompts {
+		prompt.Revision = "1"
+		db.prompts[prompt.Name] = prompt
+	}
+	return db
+}
+
+func (m *MemoryDB) Create(prompt promptsdb.Prompt) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.prompts[prompt.Name]; ok {
+		return promptsdb.ErrAlreadyExists
+	}
+	prompt.Revision = "1"
+	m.prompts[prompt.Name] = prompt
+	return nil
+}
+
+func (m *MemoryDB) Read(name string) (promptsdb.Prompt, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	prompt, ok := m.prompts[name]
+	if !ok {
+		return prompt, promptsdb.ErrNotFound
+	}
+	return prompt, nil
+}
+
+func (m *MemoryDB) List(query promptsdb.PromptQuery) ([]promptsdb.Prompt, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	prompts := []promptsdb.Prompt{}
+	for _, prompt := range m.prompts {
+		prompts = append(prompts, prompt)
+	}
+	page, total := query.Apply(prompts)
+	return page, total, nil
+}
+
+func (m *MemoryDB) Update(prompt promptsdb.Prompt) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored, ok := m.prompts[prompt.Name]
+	if !ok {
+		return promptsdb.ErrNotFound
+	}
+	if prompt.Revision != "" && prompt.Revision != stored.Revision {
+		return promptsdb.ErrConflict
+	}
+	revision, _ := strconv.Atoi(stored.Revision)
+	prompt.Revision = strconv.Itoa(revision + 1)
+	m.prompts[prompt.Name] = prompt
+	return nil
+}
+
+func (m *MemoryDB) Delete(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.prompts[name]; !ok {
+		return promptsdb.ErrNotFound
+	}
+	delete(m.prompts, name)
+	return nil
+}
+
+func (m *MemoryDB) Close() error {
+	return nil
+}
+
+func (m *MemoryDB) Setup(config promptsdb.ProviderConfiguration) error {
+	return nil
+}
+
+func newLibrary() *MemoryDB {
+	return NewMemoryDB(
+		promptsdb.Prompt{
+			Name:        "review/security_audit",
+			Title:       "Security audit",
+			Description: "Audit code for vulnerabilities",
+			Content:     "Audit this {{.language}} code",
+			Arguments:   []promptsdb.Argument{{Name: "language", Required: true}},
+			Tags:        []string{"security", "review"},
+			Extra:       map[string]any{"owner": "platform-team"},
+		},
+		promptsdb.Prompt{Name: "review/style", Title: "Style review", Content: "Check the style", Tags: []string{"review"}},
+		promptsdb.Prompt{Name: "summarize", Title: "Summarize", Description: "Summarize a security report", Content: "Summarize"},
+	)
+}
+
+func callTool(t *testing.T, handler *ToolHandler, name string, arguments map[string]any) (*mcp.CallToolResult, error) {
+	t.Helper()
+	return handler.HandleCall(context.Background(), nil, &mcp.CallToolParamsFor[map[string]any]{Name: name, Arguments: arguments})
+}
+
+func TestTools(t *testing.T) {
+	handler := NewToolHandler(newLibrary(), nil, plog.New("/tmp/test.log"))
+
+	names := []string{}
+	for _, tool := range handler.Tools() {
+		names = append(names, tool.Name)
+		assert.Equal(t, "object", tool.InputSchema.Type, tool.Name)
+	}
+
+	assert.Equal(t, []string{CREATE_PROMPT, UPDATE_PROMPT, DELETE_PROMPT, GET_PROMPT, LIST_PROMPTS, SEARCH_PROMPTS}, names)
+}
+
+func TestHandleCallGetPrompt(t *testing.T) {
+	handler := NewToolHandler(newLibrary(), nil, plog.New("/tmp/test.log"))
+
+	resp, err := callTool(t, handler, GET_PROMPT, map[string]any{"name": "review/security_audit"})
+
+	assert.NoError(t, err)
+	output, ok := resp.StructuredContent.(PromptOutput)
+	assert.True(t, ok)
+	assert.Equal(t, "Audit this {{.language}} code", output.Content)
+	assert.Equal(t, []string{"security", "review"}, output.Tags)
+	assert.Equal(t, "1", output.Revision)
+	// The schema tells callers of renderPrompt which arguments to give
+	assert.Equal(t, []string{"language"}, output.ArgumentsSchema.Required)
+	assert.Equal(t, "string", output.ArgumentsSchema.Properties["language"].Type)
+	if textContent, ok := resp.Content[0].(*mcp.TextContent); ok {
+		assert.Contains(t, textContent.Text, `"name": "review/security_audit"`)
+	} else {
+		t.Error("Expected TextContent type")
+	}
+
+	_, err = callTool(t, handler, GET_PROMPT, map[string]any{"name": "missing"})
+	assert.ErrorIs(t, err, promptsdb.ErrNotFound)
+}
+
+func TestHandleCallUpdatePrompt(t *testing.T) {
+	db := newLibrary()
+	handler := NewToolHandler(db, nil, plog.New("/tmp/test.log"))
+
+	resp, err := callTool(t, handler, UPDATE_PROMPT, map[string]any{
+		"name":     "review/security_audit",
+		"content":  "Audit this {{.language}} code carefully",
+		"tags":     []any{"security"},
+		"revision": "1",
+	})
+
+	assert.NoError(t, err)
+	output := resp.StructuredContent.(PromptOutput)
+	assert.Equal(t, "2", output.Revision)
+	assert.Equal(t, "Audit this {{.language}} code carefully", output.Content)
+
+	// Fields left out and metadata the tools do not know about are kept
+	stored, _ := db.Read("review/security_audit")
+	assert.Equal(t, "Security audit", stored.Title)
+	assert.Equal(t, []string{"security"}, stored.Tags)
+	assert.Equal(t, []promptsdb.Argument{{Name: "language", Required: true}}, stored.Arguments)
+	assert.Equal(t, map[string]any{"owner": "platform-team"}, stored.Extra)
+
+	// Changes based on an old revision are refused
+	_, err = callTool(t, handler, UPDATE_PROMPT, map[string]any{"name": "review/security_audit", "title": "Stale", "revision": "1"})
+	assert.ErrorIs(t, err, promptsdb.ErrConflict)
+
+	resp, err = callTool(t, handler, UPDATE_PROMPT, map[string]any{
+		"name":      "summarize",
+		"arguments": []any{map[string]any{"name": "report", "description": "The report", "required": true}},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []promptsdb.Argument{{Name: "report", Description: "The report", Required: true}}, resp.StructuredContent.(PromptOutput).Arguments)
+
+	// A prompt can be turned into a partial or made to extend another one
+	resp, err = callTool(t, handler, UPDATE_PROMPT, map[string]any{"name": "review/style", "partial": true})
+	assert.NoError(t, err)
+	assert.True(t, resp.StructuredContent.(PromptOutput).Partial)
+
+	resp, err = callTool(t, handler, UPDATE_PROMPT, map[string]any{"name": "summarize", "extends": "review/style", "content": `{{define "focus"}}Summaries{{end}}`})
+	assert.NoError(t, err)
+	assert.Equal(t, "review/style", resp.StructuredContent.(PromptOutput).Extends)
+
+	_, err = callTool(t, handler, UPDATE_PROMPT, map[string]any{"name": "missing", "title": "Missing"})
+	assert.ErrorIs(t, err, promptsdb.ErrNotFound)
+}
+
+func TestHandleCallDeletePrompt(t *testing.T) {
+	db := newLibrary()
+	handler := NewToolHandler(db, nil, plog.New("/tmp/test.log"))
+
+	resp, err := callTool(t, handler, DELETE_PROMPT, map[string]any{"name": "summarize"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"name": "summarize"}, resp.StructuredContent)
+
+	_, err = db.Read("summarize")
+	assert.ErrorIs(t, err, promptsdb.ErrNotFound)
+
+	_, err = callTool(t, handler, DELETE_PROMPT, map[string]any{"name": "summarize"})
+	assert.ErrorIs(t, err, promptsdb.ErrNotFound)
+}
+
+func TestHandleCallListPrompts(t *testing.T) {
+	handler := NewToolHandler(newLibrary(), nil, plog.New("/tmp/test.log"))
+
+	tests := []struct {
+		name       string
+		arguments  map[string]any
+		expected   []string
+		total      int
+		nextOffset int
+	}{
+		{"all", map[string]any{}, []string{"review/security_audit", "review/style", "summarize"}, 3, 0},
+		{"namespace", map[string]any{"namespace": "review"}, []string{"review/security_audit", "review/style"}, 2, 0},
+		{"tag", map[string]any{"tag": "security"}, []string{"review/security_audit"}, 1, 0},
+		{"name contains", map[string]any{"nameContains": "style"}, []string{"review/style"}, 1, 0},
+		{"first page", map[string]any{"limit": 2}, []string{"review/security_audit", "review/style"}, 3, 2},
+		{"last page", map[string]any{"offset": 2, "limit": 2}, []string{"summarize"}, 3, 0},
+	}
+
+	for _, test := range tests {
+		resp, err := callTool(t, handler, LIST_PROMPTS, test.arguments)
+		assert.NoError(t, err, test.name)
+
+		output := resp.StructuredContent.(PromptsOutput)
+		names := []string{}
+		for _, prompt := range output.Prompts {
+			names = append(names, prompt.Name)
+		}
+
+		assert.Equal(t, test.expected, names, test.name)
+		assert.Equal(t, test.total, output.Total, test.name)
+		assert.Equal(t, test.nextOffset, output.NextOffset, test.name)
+	}
+}
+
+func TestHandleCallSearchPrompts(t *testing.T) {
+	handler := NewToolHandler(newLibrary(), nil, plog.New("/tmp/test.log"))
+
+	tests := []struct {
+		query    string
+		limit    int
+		expected []string
+	}{
+		// The name match ranks above the description match
+		{"security", 0, []string{"review/security_audit", "summarize"}},
+		{"SECURITY report", 0, []string{"summarize"}},
+		// Both names match, the title of the style review matches as well
+		{"review", 1, []string{"review/style"}},
+		{"nothing", 0, []string{}},
+	}
+
+	for _, test := range tests {
+		arguments := map[string]any{"query": test.query}
+		if test.limit > 0 {
+			arguments["limit"] = test.limit
+		}
+
+		resp, err := callTool(t, handler, SEARCH_PROMPTS, arguments)
+		assert.NoError(t, err, test.query)
+
+		names := []string{}
+		for _, prompt := range resp.StructuredContent.(PromptsOutput).Prompts {
+			names = append(names, prompt.Name)
+		}
+
+		assert.Equal(t, test.expected, names, test.query)
+	}
+
+	_, err := callTool(t, handler, SEARCH_PROMPTS, map[string]any{"query": "  "})
+	assert.Error(t, err)
+}
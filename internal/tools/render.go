@@ -0,0 +1,164 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hkionline/prompter/internal/plog"
+	"github.com/modelcontextprotocol/go-sdk/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// RenderOutput is the structured output of the renderPrompt tool
+type RenderOutput struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Messages    []RenderedMessage `json:"messages"`
+}
+
+// RenderedMessage is a single rendered message of a prompt, either text, an image or an embedded file
+type RenderedMessage struct {
+	Role     string `json:"role"`
+	Type     string `json:"type"` // text, image or resource like the MCP content types
+	Text     string `json:"text,omitempty"`
+	MIMEType string `json:"mimeType,omitempty"`
+	URI      string `json:"uri,omitempty"`
+	Data     []byte `json:"data,omitempty"` // base64 encoded contents of images and binary files
+}
+
+// RenderPromptTool creates the tool definition for rendering prompts
+func (h *ToolHandler) RenderPromptTool() *mcp.Tool {
+	return &mcp.Tool{
+		Name:        RENDER_PROMPT,
+		Title:       "Render prompt",
+		Description: "Render a prompt with the given arguments exactly like prompts/get does and return its messages",
+		InputSchema: &jsonschema.Schema{
+			Type: "object",
+			Properties: map[string]*jsonschema.Schema{
+				"name": stringSchema("Name of the prompt to render."),
+				"arguments": {
+					Type:        "object",
+					Description: "Values of the prompt arguments by argument name, typed as the argumentsSchema returned by getPrompt describes, e.g. true for booleans and arrays for lists. Strings are converted to the argument type.",
+				},
+			},
+			Required: []string{"name"},
+		},
+		OutputSchema: &jsonschema.Schema{
+			Type: "object",
+			Properties: map[string]*jsonschema.Schema{
+				"name":        stringSchema("Name of the rendered prompt."),
+				"description": stringSchema("Human readable explanation of the prompt."),
+				"messages": {
+					Type:        "array",
+					Description: "Rendered messages in the order they are sent.",
+					Items: &jsonschema.Schema{
+						Type: "object",
+						Properties: map[string]*jsonschema.Schema{
+							"role":     stringSchema("Who the message is from, user or assistant."),
+							"type":     {Type: "string", Description: "Type of the message content.", Enum: []any{"text", "image", "resource"}},
+							"text":     stringSchema("Text of the message or of the embedded file."),
+							"mimeType": stringSchema("Media type of the image or the embedded file."),
+							"uri":      stringSchema("URI of the embedded file."),
+							"data":     stringSchema("Base64 encoded image or binary file."),
+						},
+						Required: []string{"role", "type"},
+					},
+				},
+			},
+			Required: []string{"name", "messages"},
+		},
+	}
+}
+
+// handleRenderPrompt handles the renderPrompt tool call. The content of every rendered
+// message is returned as is and the messages with their roles as structured content.
+func (h *ToolHandler) handleRenderPrompt(ctx context.Context, req *mcp.CallToolParamsFor[map[string]any]) (*mcp.CallToolResult, error) {
+
+	var input struct {
+		Name      string         `json:"name"`
+		Arguments map[string]any `json:"arguments"`
+	}
+
+	if err := decodeArguments(req.Arguments, &input); err != nil {
+		h.logger.Write(plog.SERVER, "Invalid arguments for renderPrompt: %s", err.Error())
+		return nil, err
+	}
+
+	// prompts/get takes the arguments as text and converts them back to the argument types
+	arguments := map[string]string{}
+	for name, value := range input.Arguments {
+		text, err := argumentText(value)
+		if err != nil {
+			h.logger.Write(plog.SERVER, "Invalid argument %s for renderPrompt: %s", name, err.Error())
+			return nil, fmt.Errorf("invalid argument %s: %w", name, err)
+		}
+		arguments[name] = text
+	}
+
+	rendered, err := h.renderer.HandleGet(ctx, nil, &mcp.GetPromptParams{
+		Name:      input.Name,
+		Arguments: arguments,
+	})
+	if err != nil {
+		h.logger.Write(plog.SERVER, "Failed to render prompt: %s", err.Error())
+		return nil, fmt.Errorf("failed to render prompt: %w", err)
+	}
+
+	output := RenderOutput{
+		Name:        input.Name,
+		Description: rendered.Description,
+		Messages:    []RenderedMessage{},
+	}
+
+	result := &mcp.CallToolResult{}
+
+	for _, message := range rendered.Messages {
+
+		renderedMessage := RenderedMessage{Role: string(message.Role)}
+
+		switch content := message.Content.(type) {
+		case *mcp.TextContent:
+			renderedMessage.Type = "text"
+			renderedMessage.Text = content.Text
+		case *mcp.ImageContent:
+			renderedMessage.Type = "image"
+			renderedMessage.MIMEType = content.MIMEType
+			renderedMessage.Data = content.Data
+		case *mcp.EmbeddedResource:
+			renderedMessage.Type = "resource"
+			renderedMessage.URI = content.Resource.URI
+			renderedMessage.MIMEType = content.Resource.MIMEType
+			renderedMessage.Text = content.Resource.Text
+			renderedMessage.Data = content.Resource.Blob
+		default:
+			return nil, fmt.Errorf("prompt %s has a message of unsupported type %T", input.Name, content)
+		}
+
+		output.Messages = append(output.Messages, renderedMessage)
+		result.Content = append(result.Content, message.Content)
+	}
+
+	result.StructuredContent = output
+
+	return result, nil
+}
+
+// argumentText converts a tool argument value to the text prompts/get takes, strings are
+// kept as is and other values are written as JSON
+func argumentText(value any) (string, error) {
+
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	}
+
+	text, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	return string(text), nil
+}
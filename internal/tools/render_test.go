@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/hkionline/prompter/internal/plog"
+	"github.com/hkionline/prompter/internal/prompts"
+	"github.com/hkionline/prompter/internal/promptsdb"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRenderHandler() *ToolHandler {
+	db := NewMemoryDB(promptsdb.Prompt{
+		Name:        "sentiment",
+		Description: "Classify sentiment",
+		Arguments:   []promptsdb.Argument{{Name: "review", Required: true}},
+		Messages: []promptsdb.Message{
+			{Role: promptsdb.ROLE_USER, Content: "Classify: great"},
+			{Role: promptsdb.ROLE_ASSISTANT, Content: "positive"},
+		},
+		Content: "Classify: {{.review}}",
+	})
+	logger := plog.New("/tmp/test.log")
+
+	return NewToolHandler(db, prompts.NewPromptHandler(db, prompts.Configuration{}, logger), logger)
+}
+
+func TestHandleCallRenderPrompt(t *testing.T) {
+	handler := newRenderHandler()
+
+	resp, err := callTool(t, handler, RENDER_PROMPT, map[string]any{
+		"name":      "sentiment",
+		"arguments": map[string]any{"review": "too slow"},
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, resp.Content, 3)
+	if textContent, ok := resp.Content[2].(*mcp.TextContent); ok {
+		assert.Equal(t, "Classify: too slow", textContent.Text)
+	} else {
+		t.Error("Expected TextContent type")
+	}
+
+	assert.Equal(t, RenderOutput{
+		Name:        "sentiment",
+		Description: "Classify sentiment",
+		Messages: []RenderedMessage{
+			{Role: "user", Type: "text", Text: "Classify: great"},
+			{Role: "assistant", Type: "text", Text: "positive"},
+			{Role: "user", Type: "text", Text: "Classify: too slow"},
+		},
+	}, resp.StructuredContent)
+}
+
+func TestHandleCallRenderPromptErrors(t *testing.T) {
+	handler := newRenderHandler()
+
+	_, err := callTool(t, handler, RENDER_PROMPT, map[string]any{"name": "sentiment"})
+	assert.ErrorContains(t, err, "review")
+
+	// The error of prompts/get keeps its message but not the cause
+	_, err = callTool(t, handler, RENDER_PROMPT, map[string]any{"name": "missing"})
+	assert.ErrorContains(t, err, promptsdb.ErrNotFound.Error())
+
+	// Without a renderer there is no tool to call
+	_, err = callTool(t, NewToolHandler(newLibrary(), nil, plog.New("/tmp/test.log")), RENDER_PROMPT, map[string]any{"name": "sentiment"})
+	assert.ErrorContains(t, err, "unsupported tool")
+}
+
+func TestHandleCallRenderPromptTypedArguments(t *testing.T) {
+	db := NewMemoryDB(promptsdb.Prompt{
+		Name: "code_review",
+		Arguments: []promptsdb.Argument{
+			{Name: "verbose", Type: promptsdb.ARGUMENT_BOOLEAN},
+			{Name: "files", Type: promptsdb.ARGUMENT_LIST},
+			{Name: "depth", Type: promptsdb.ARGUMENT_INTEGER},
+		},
+		Content: "Review{{range .files}} {{.}}{{end}} {{.depth}} levels deep.{{if .verbose}} Explain every finding.{{end}}",
+	})
+	logger := plog.New("/tmp/test.log")
+	handler := NewToolHandler(db, prompts.NewPromptHandler(db, prompts.Configuration{}, logger), logger)
+
+	// JSON values and text are both converted to the argument types
+	for _, arguments := range []map[string]any{
+		{"verbose": true, "files": []any{"main.go", "go.mod"}, "depth": 2},
+		{"verbose": "true", "files": "main.go,go.mod", "depth": "2"},
+	} {
+		resp, err := callTool(t, handler, RENDER_PROMPT, map[string]any{"name": "code_review", "arguments": arguments})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Review main.go go.mod 2 levels deep. Explain every finding.", resp.StructuredContent.(RenderOutput).Messages[0].Text)
+	}
+
+	_, err := callTool(t, handler, RENDER_PROMPT, map[string]any{"name": "code_review", "arguments": map[string]any{"depth": 2.5}})
+	assert.ErrorContains(t, err, `argument depth: "2.5" is not a whole number`)
+}
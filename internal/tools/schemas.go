@@ -0,0 +1,232 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hkionline/prompter/internal/promptsdb"
+	"github.com/modelcontextprotocol/go-sdk/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// PromptOutput is the structured output of the tools returning a whole prompt
+type PromptOutput struct {
+	Name        string               `json:"name"`
+	Title       string               `json:"title,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Arguments   []promptsdb.Argument `json:"arguments,omitempty"`
+	Messages    []promptsdb.Message  `json:"messages,omitempty"`
+	Extends     string               `json:"extends,omitempty"`
+	Content     string               `json:"content"`
+	Tags        []string             `json:"tags,omitempty"`
+	Partial     bool                 `json:"partial,omitempty"`
+	Source      string               `json:"source,omitempty"`
+	Revision    string               `json:"revision,omitempty"`
+	// ArgumentsSchema describes the values the arguments accept, e.g. for renderPrompt
+	ArgumentsSchema *jsonschema.Schema `json:"argumentsSchema,omitempty"`
+}
+
+// PromptSummary is the structured output of a prompt in the tools listing prompts
+type PromptSummary struct {
+	Name        string   `json:"name"`
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// PromptsOutput is the structured output of the tools listing prompts
+type PromptsOutput struct {
+	Prompts    []PromptSummary `json:"prompts"`
+	Total      int             `json:"total"`                // number of prompts matching, also those left out of the page
+	NextOffset int             `json:"nextOffset,omitempty"` // offset of the next page when there are prompts left
+}
+
+// toPromptOutput converts a stored prompt to the structured tool output
+func toPromptOutput(prompt promptsdb.Prompt) PromptOutput {
+
+	output := PromptOutput{
+		Name:        prompt.Name,
+		Title:       prompt.Title,
+		Description: prompt.Description,
+		Arguments:   prompt.Arguments,
+		Messages:    prompt.Messages,
+		Extends:     prompt.Extends,
+		Content:     prompt.Content,
+		Tags:        prompt.Tags,
+		Partial:     prompt.Partial,
+		Source:      prompt.Source,
+		Revision:    prompt.Revision,
+	}
+
+	// The providers validate the arguments, a schema which can not be built is left out
+	if len(prompt.Arguments) > 0 {
+		if schema, err := prompt.ArgumentsSchema(); err == nil {
+			output.ArgumentsSchema = schema
+		}
+	}
+
+	return output
+}
+
+// toPromptSummary converts a stored prompt to the structured tool output used in listings
+func toPromptSummary(prompt promptsdb.Prompt) PromptSummary {
+	return PromptSummary{
+		Name:        prompt.Name,
+		Title:       prompt.Title,
+		Description: prompt.Description,
+		Tags:        prompt.Tags,
+	}
+}
+
+// structuredResult returns the output as structured content together with its JSON
+// as text for the clients not reading structured content
+func structuredResult(output any) (*mcp.CallToolResult, error) {
+
+	text, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{
+			&mcp.TextContent{
+				Text: string(text),
+			},
+		},
+		StructuredContent: output,
+	}, nil
+}
+
+// decodeArguments reads the tool call arguments into the input struct of the tool,
+// the SDK has validated the arguments against the input schema already
+func decodeArguments(arguments map[string]any, input any) error {
+
+	data, err := json.Marshal(arguments)
+	if err != nil {
+		return err
+	}
+
+	if err = json.Unmarshal(data, input); err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	return nil
+}
+
+// stringSchema describes a string property
+func stringSchema(description string) *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type:        "string",
+		Description: description,
+	}
+}
+
+// stringsSchema describes a list of strings property
+func stringsSchema(description string) *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type:        "array",
+		Description: description,
+		Items:       &jsonschema.Schema{Type: "string"},
+	}
+}
+
+// argumentsSchema describes the arguments of a prompt
+func argumentsSchema(description string) *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type:        "array",
+		Description: description,
+		Items: &jsonschema.Schema{
+			Type: "object",
+			Properties: map[string]*jsonschema.Schema{
+				"name":        stringSchema("Name of the argument as used in the prompt template, e.g. {{.language}} for language."),
+				"description": stringSchema("Human readable explanation of the argument."),
+				"required":    {Type: "boolean", Description: "Whether the prompt can not be invoked without the argument."},
+				"values":      stringsSchema("The only values the argument accepts."),
+				"examples":    stringsSchema("Example values suggested when completing the argument."),
+				"sensitive":   {Type: "boolean", Description: "Whether the values, such as tokens, are kept out of the completion history."},
+				"type": {
+					Type:        "string",
+					Description: "Type the argument value is converted to before rendering, string when not given.",
+					Enum: []any{promptsdb.ARGUMENT_STRING, promptsdb.ARGUMENT_NUMBER, promptsdb.ARGUMENT_INTEGER, promptsdb.ARGUMENT_BOOLEAN,
+						promptsdb.ARGUMENT_ENUM, promptsdb.ARGUMENT_LIST, promptsdb.ARGUMENT_OBJECT},
+				},
+				"default":    {Description: "Value used when the argument is not given."},
+				"minimum":    {Type: "number", Description: "Smallest value of a number argument."},
+				"maximum":    {Type: "number", Description: "Largest value of a number argument."},
+				"min_length": {Type: "integer", Description: "Fewest characters of a string argument."},
+				"max_length": {Type: "integer", Description: "Most characters of a string argument."},
+				"pattern":    stringSchema("Regular expression a string argument must match."),
+				"items": {
+					Type:        "string",
+					Description: "Type of the items of a list argument, string when not given.",
+					Enum:        []any{promptsdb.ARGUMENT_STRING, promptsdb.ARGUMENT_NUMBER, promptsdb.ARGUMENT_INTEGER, promptsdb.ARGUMENT_BOOLEAN},
+				},
+				"min_items": {Type: "integer", Description: "Fewest items of a list argument."},
+				"max_items": {Type: "integer", Description: "Most items of a list argument."},
+				"schema":    {Type: "object", Description: "JSON Schema of an object argument."},
+			},
+			Required: []string{"name"},
+		},
+	}
+}
+
+// promptOutputSchema describes PromptOutput
+func promptOutputSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type: "object",
+		Properties: map[string]*jsonschema.Schema{
+			"name":        stringSchema("Name of the prompt."),
+			"title":       stringSchema("Human readable title of the prompt."),
+			"description": stringSchema("Human readable explanation of the prompt."),
+			"arguments":   argumentsSchema("Arguments the prompt is invoked with."),
+			"messages": {
+				Type:        "array",
+				Description: "Conversation turns sent before the content.",
+				Items: &jsonschema.Schema{
+					Type: "object",
+					Properties: map[string]*jsonschema.Schema{
+						"role":    stringSchema("Who the turn is from, user or assistant."),
+						"content": stringSchema("Text of the turn."),
+						"file":    stringSchema("File sent as the turn."),
+					},
+				},
+			},
+			"extends":  stringSchema("Name of the base prompt whose blocks the content redefines."),
+			"content":  stringSchema("Template of the prompt."),
+			"tags":     stringsSchema("Tags of the prompt."),
+			"partial":  {Type: "boolean", Description: "Whether the prompt is a partial only included in other prompts."},
+			"source":   stringSchema("Where the prompt was loaded from."),
+			"revision": stringSchema("Revision of the stored prompt, pass it to updatePrompt to detect concurrent changes."),
+			"argumentsSchema": {
+				Type:        "object",
+				Description: "JSON Schema of the object of arguments renderPrompt accepts for the prompt.",
+			},
+		},
+		Required: []string{"name", "content"},
+	}
+}
+
+// promptsOutputSchema describes PromptsOutput
+func promptsOutputSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type: "object",
+		Properties: map[string]*jsonschema.Schema{
+			"prompts": {
+				Type: "array",
+				Items: &jsonschema.Schema{
+					Type: "object",
+					Properties: map[string]*jsonschema.Schema{
+						"name":        stringSchema("Name of the prompt."),
+						"title":       stringSchema("Human readable title of the prompt."),
+						"description": stringSchema("Human readable explanation of the prompt."),
+						"tags":        stringsSchema("Tags of the prompt."),
+					},
+					Required: []string{"name"},
+				},
+			},
+			"total":      {Type: "integer", Description: "Number of prompts matching."},
+			"nextOffset": {Type: "integer", Description: "Offset of the next page, missing on the last page."},
+		},
+		Required: []string{"prompts", "total"},
+	}
+}
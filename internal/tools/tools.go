@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"fmt"
+	"regexp"
 
 	"github.com/hkionline/prompter/internal/plog"
 	"github.com/hkionline/prompter/internal/promptsdb"
@@ -11,21 +12,88 @@ import (
 )
 
 const (
-	CREATE_PROMPT = "saveNewPrompt" // tool call name for creating and storing a new prompt
+	CREATE_PROMPT  = "saveNewPrompt" // tool call name for creating and storing a new prompt
+	UPDATE_PROMPT  = "updatePrompt"  // tool call name for changing a stored prompt
+	DELETE_PROMPT  = "deletePrompt"  // tool call name for removing a stored prompt
+	GET_PROMPT     = "getPrompt"     // tool call name for reading a stored prompt
+	LIST_PROMPTS   = "listPrompts"   // tool call name for listing the stored prompts
+	SEARCH_PROMPTS = "searchPrompts" // tool call name for searching the stored prompts
+	RENDER_PROMPT  = "renderPrompt"  // tool call name for rendering a prompt with arguments
+
+	PROMPT_NAME_PATTERN = `^[A-Za-z0-9_-]+(/[A-Za-z0-9_-]+)*$` // names of the prompts created by tools, slashes separate namespaces
 )
 
+var promptNamePattern = regexp.MustCompile(PROMPT_NAME_PATTERN)
+
+// PromptRenderer renders prompts with their arguments, the tools render prompts
+// with the prompts/get handler so that the tools and prompts/get render alike
+type PromptRenderer interface {
+	HandleGet(ctx context.Context, ss *mcp.ServerSession, req *mcp.GetPromptParams) (*mcp.GetPromptResult, error)
+}
+
+// toolCall handles the call of a single tool
+type toolCall func(ctx context.Context, req *mcp.CallToolParamsFor[map[string]any]) (*mcp.CallToolResult, error)
+
+// registeredTool pairs a tool definition with the handler of its calls
+type registeredTool struct {
+	tool   *mcp.Tool
+	handle toolCall
+}
+
 // ToolHandler handles MCP tool requests
 type ToolHandler struct {
-	db     promptsdb.Provider
-	logger *plog.Plogger
+	db       promptsdb.Provider
+	renderer PromptRenderer
+	logger   *plog.Plogger
+	registry map[string]registeredTool // tools identified by name
+	order    []string                  // tool names in the order they were registered
 }
 
-// NewToolHandler creates a new ToolHandler instance
-func NewToolHandler(db promptsdb.Provider, logger *plog.Plogger) *ToolHandler {
-	return &ToolHandler{
-		db:     db,
-		logger: logger,
+// NewToolHandler creates a new ToolHandler instance, the renderPrompt tool is
+// only available when a renderer is given
+func NewToolHandler(db promptsdb.Provider, renderer PromptRenderer, logger *plog.Plogger) *ToolHandler {
+
+	h := &ToolHandler{
+		db:       db,
+		renderer: renderer,
+		logger:   logger,
+		registry: map[string]registeredTool{},
 	}
+
+	h.register(h.CreatePromptTool(), h.handleSaveNewPrompt)
+	h.register(h.UpdatePromptTool(), h.handleUpdatePrompt)
+	h.register(h.DeletePromptTool(), h.handleDeletePrompt)
+	h.register(h.GetPromptTool(), h.handleGetPrompt)
+	h.register(h.ListPromptsTool(), h.handleListPrompts)
+	h.register(h.SearchPromptsTool(), h.handleSearchPrompts)
+
+	if renderer != nil {
+		h.register(h.RenderPromptTool(), h.handleRenderPrompt)
+	}
+
+	return h
+}
+
+// register adds the tool to the tools HandleCall dispatches to
+func (h *ToolHandler) register(tool *mcp.Tool, handle toolCall) {
+
+	if _, ok := h.registry[tool.Name]; !ok {
+		h.order = append(h.order, tool.Name)
+	}
+
+	h.registry[tool.Name] = registeredTool{tool: tool, handle: handle}
+}
+
+// Tools returns the definitions of the registered tools in the order they were registered
+func (h *ToolHandler) Tools() []*mcp.Tool {
+
+	tools := []*mcp.Tool{}
+
+	for _, name := range h.order {
+		tools = append(tools, h.registry[name].tool)
+	}
+
+	return tools
 }
 
 // CreatePromptTool creates the tool definition for creating prompts
@@ -40,7 +108,8 @@ func (h *ToolHandler) CreatePromptTool() *mcp.Tool {
 			Properties: map[string]*jsonschema.Schema{
 				"name": {
 					Type:        "string",
-					Description: "Computer readable name for the prompt. White space should be replaced with underscores and special characters omitted.",
+					Description: "Computer readable name for the prompt. White space should be replaced with underscores and special characters omitted. Slashes separate namespaces, e.g. review/security_audit.",
+					Pattern:     PROMPT_NAME_PATTERN,
 				},
 				"title": {
 					Type:        "string",
@@ -52,10 +121,22 @@ func (h *ToolHandler) CreatePromptTool() *mcp.Tool {
 				},
 				"content": {
 					Type:        "string",
-					Description: "Full content of the prompt to be created and stored.",
+					Description: "Full content of the prompt to be created and stored. Arguments are used as Go template fields, e.g. {{.language}}.",
+				},
+				"arguments": argumentsSchema("Arguments the prompt is invoked with, every argument used in the content should be listed."),
+				"tags":      stringsSchema("Tags for finding the prompt."),
+				"extends": {
+					Type:        "string",
+					Description: "Name of a base prompt to extend. The content then only redefines blocks of the base prompt, e.g. {{define \"focus\"}}Look for races.{{end}}.",
+				},
+				"partial": {
+					Type:        "boolean",
+					Description: "Whether the prompt is a partial, such as shared style rules, only included in other prompts with {{include \"name\"}} and not listed to clients.",
 				},
 			},
+			Required: []string{"name", "content"},
 		},
+		OutputSchema: promptOutputSchema(),
 	}
 }
 
@@ -63,39 +144,62 @@ func (h *ToolHandler) CreatePromptTool() *mcp.Tool {
 func (h *ToolHandler) HandleCall(ctx context.Context, ss *mcp.ServerSession, req *mcp.CallToolParamsFor[map[string]any]) (*mcp.CallToolResult, error) {
 	h.logger.Write(plog.CLIENT, "tools/call")
 
-	if req.Name == CREATE_PROMPT {
-		return h.handleSaveNewPrompt(req)
+	registered, ok := h.registry[req.Name]
+	if !ok {
+		h.logger.Write(plog.SERVER, "Unsupported tool: %s", req.Name)
+		return nil, fmt.Errorf("unsupported tool: %s", req.Name)
 	}
 
-	h.logger.Write(plog.SERVER, "Unsupported tool: %s", req.Name)
-	return nil, fmt.Errorf("unsupported tool: %s", req.Name)
+	return registered.handle(ctx, req)
 }
 
 // handleSaveNewPrompt handles the saveNewPrompt tool call
-func (h *ToolHandler) handleSaveNewPrompt(req *mcp.CallToolParamsFor[map[string]any]) (*mcp.CallToolResult, error) {
+func (h *ToolHandler) handleSaveNewPrompt(ctx context.Context, req *mcp.CallToolParamsFor[map[string]any]) (*mcp.CallToolResult, error) {
 	if req.Arguments == nil {
 		h.logger.Write(plog.SERVER, "Missing arguments for saveNewPrompt")
 		return nil, fmt.Errorf("missing arguments for saveNewPrompt")
 	}
 
-	// Extract arguments from the map
-	args := req.Arguments
-	name, _ := args["name"].(string)
-	title, _ := args["title"].(string)
-	description, _ := args["description"].(string)
-	content, _ := args["content"].(string)
+	var input struct {
+		Name        string               `json:"name"`
+		Title       string               `json:"title"`
+		Description string               `json:"description"`
+		Content     string               `json:"content"`
+		Arguments   []promptsdb.Argument `json:"arguments"`
+		Tags        []string             `json:"tags"`
+		Extends     string               `json:"extends"`
+		Partial     bool                 `json:"partial"`
+	}
+
+	if err := decodeArguments(req.Arguments, &input); err != nil {
+		h.logger.Write(plog.SERVER, "Invalid arguments for saveNewPrompt: %s", err.Error())
+		return nil, err
+	}
+
+	name := input.Name
+	title := input.Title
 
 	if name == "" {
 		h.logger.Write(plog.SERVER, "Missing prompt name")
 		return nil, fmt.Errorf("missing prompt name")
 	}
 
+	// The schema pattern is checked here as well for callers bypassing the SDK
+	if !promptNamePattern.MatchString(name) {
+		h.logger.Write(plog.SERVER, "Invalid prompt name: %s", name)
+		return nil, fmt.Errorf("invalid prompt name %s, use letters, digits, underscores and hyphens with slashes separating namespaces", name)
+	}
+
 	// Create new prompt
 	prompt := promptsdb.Prompt{
 		Name:        name,
 		Title:       title,
-		Description: description,
-		Content:     content,
+		Description: input.Description,
+		Content:     input.Content,
+		Arguments:   input.Arguments,
+		Tags:        input.Tags,
+		Extends:     input.Extends,
+		Partial:     input.Partial,
 	}
 
 	err := h.db.Create(prompt)
@@ -104,6 +208,11 @@ func (h *ToolHandler) handleSaveNewPrompt(req *mcp.CallToolParamsFor[map[string]
 		return nil, fmt.Errorf("failed to create prompt: %w", err)
 	}
 
+	// The provider gives the stored prompt its revision and source
+	if created, err := h.db.Read(name); err == nil && created.Name == name {
+		prompt = created
+	}
+
 	responseText := fmt.Sprintf("created new prompt with name '%s' and title '%s'", name, title)
 
 	return &mcp.CallToolResult{
@@ -112,6 +221,7 @@ func (h *ToolHandler) handleSaveNewPrompt(req *mcp.CallToolParamsFor[map[string]
 				Text: responseText,
 			},
 		},
-		IsError: false,
+		StructuredContent: toPromptOutput(prompt),
+		IsError:           false,
 	}, nil
 }
@@ -45,8 +45,8 @@ func (m *MockDB) Read(name string) (promptsdb.Prompt, error) {
 	return promptsdb.Prompt{}, nil
 }
 
-func (m *MockDB) List(query promptsdb.PromptQuery) ([]promptsdb.Prompt, error) {
-	return []promptsdb.Prompt{}, nil
+func (m *MockDB) List(query promptsdb.PromptQuery) ([]promptsdb.Prompt, int, error) {
+	return []promptsdb.Prompt{}, 0, nil
 }
 
 func (m *MockDB) Update(prompt promptsdb.Prompt) error {
@@ -69,7 +69,7 @@ func TestNewToolHandler(t *testing.T) {
 	db := &MockDB{}
 	logger := plog.New("/tmp/test.log")
 
-	handler := NewToolHandler(db, logger)
+	handler := NewToolHandler(db, nil, logger)
 
 	assert.NotNil(t, handler)
 	assert.Equal(t, db, handler.db)
@@ -81,7 +81,7 @@ func TestHandleList(t *testing.T) {
 
 	db := &MockDB{}
 	logger := plog.New("/tmp/test.log")
-	handler := NewToolHandler(db, logger)
+	handler := NewToolHandler(db, nil, logger)
 
 	tool := handler.CreatePromptTool()
 
@@ -97,7 +97,7 @@ func TestHandleCallSaveNewPrompt(t *testing.T) {
 
 	db := &MockDB{}
 	logger := plog.New("/tmp/test.log")
-	handler := NewToolHandler(db, logger)
+	handler := NewToolHandler(db, nil, logger)
 
 	req := &mcp.CallToolParamsFor[map[string]any]{
 		Name: CREATE_PROMPT,
@@ -127,7 +127,7 @@ func TestHandleCallSaveNewPromptError(t *testing.T) {
 
 	db := &MockDB{}
 	logger := plog.New("/tmp/test.log")
-	handler := NewToolHandler(db, logger)
+	handler := NewToolHandler(db, nil, logger)
 
 	req := &mcp.CallToolParamsFor[map[string]any]{
 		Name: CREATE_PROMPT,
@@ -146,7 +146,7 @@ func TestHandleCallSaveNewPromptMissingName(t *testing.T) {
 
 	db := &MockDB{}
 	logger := plog.New("/tmp/test.log")
-	handler := NewToolHandler(db, logger)
+	handler := NewToolHandler(db, nil, logger)
 
 	req := &mcp.CallToolParamsFor[map[string]any]{
 		Name: CREATE_PROMPT,
@@ -165,7 +165,7 @@ func TestHandleCallSaveNewPromptMissingArguments(t *testing.T) {
 
 	db := &MockDB{}
 	logger := plog.New("/tmp/test.log")
-	handler := NewToolHandler(db, logger)
+	handler := NewToolHandler(db, nil, logger)
 
 	req := &mcp.CallToolParamsFor[map[string]any]{
 		Name: CREATE_PROMPT,
@@ -176,12 +176,78 @@ func TestHandleCallSaveNewPromptMissingArguments(t *testing.T) {
 	assert.Nil(t, resp)
 }
 
+func TestHandleCallSaveNewPromptDetails(t *testing.T) {
+	db := NewMemoryDB()
+	handler := NewToolHandler(db, nil, plog.New("/tmp/test.log"))
+
+	resp, err := callTool(t, handler, CREATE_PROMPT, map[string]any{
+		"name":    "review/code_review",
+		"title":   "Code review",
+		"content": "Review this {{.language}} code",
+		"arguments": []any{
+			map[string]any{"name": "language", "description": "Language of the code", "required": true},
+		},
+		"tags": []any{"review", "code"},
+	})
+
+	assert.NoError(t, err)
+	assert.False(t, resp.IsError)
+
+	output, ok := resp.StructuredContent.(PromptOutput)
+	assert.True(t, ok)
+	assert.Equal(t, "review/code_review", output.Name)
+	assert.Equal(t, "1", output.Revision)
+	assert.Equal(t, []promptsdb.Argument{{Name: "language", Description: "Language of the code", Required: true}}, output.Arguments)
+	assert.Equal(t, []string{"review", "code"}, output.Tags)
+
+	stored, err := db.Read("review/code_review")
+	assert.NoError(t, err)
+	assert.Equal(t, output.Arguments, stored.Arguments)
+	assert.Equal(t, output.Tags, stored.Tags)
+	assert.False(t, stored.Partial)
+
+	// Partials are marked as such
+	resp, err = callTool(t, handler, CREATE_PROMPT, map[string]any{"name": "shared/style_guide", "content": "Be concise", "partial": true})
+
+	assert.NoError(t, err)
+	assert.True(t, resp.StructuredContent.(PromptOutput).Partial)
+}
+
+func TestHandleCallSaveNewPromptInvalidName(t *testing.T) {
+	db := NewMemoryDB()
+	handler := NewToolHandler(db, nil, plog.New("/tmp/test.log"))
+
+	names := []string{"../escape", "with space", "/absolute", "trailing/", "a//b", "semi;colon"}
+
+	for _, name := range names {
+		resp, err := callTool(t, handler, CREATE_PROMPT, map[string]any{"name": name, "content": "Content"})
+
+		assert.ErrorContains(t, err, "invalid prompt name", name)
+		assert.Nil(t, resp, name)
+	}
+
+	_, total, _ := db.List(promptsdb.PromptQuery{})
+	assert.Equal(t, 0, total)
+}
+
+func TestCreatePromptToolSchema(t *testing.T) {
+	handler := NewToolHandler(&MockDB{}, nil, plog.New("/tmp/test.log"))
+
+	tool := handler.CreatePromptTool()
+
+	assert.Equal(t, []string{"name", "content"}, tool.InputSchema.Required)
+	assert.Equal(t, PROMPT_NAME_PATTERN, tool.InputSchema.Properties["name"].Pattern)
+	assert.Contains(t, tool.InputSchema.Properties, "arguments")
+	assert.Contains(t, tool.InputSchema.Properties, "tags")
+	assert.NotNil(t, tool.OutputSchema)
+}
+
 func TestHandleCallUnsupportedTool(t *testing.T) {
 	setupTestServer()
 
 	db := &MockDB{}
 	logger := plog.New("/tmp/test.log")
-	handler := NewToolHandler(db, logger)
+	handler := NewToolHandler(db, nil, logger)
 
 	req := &mcp.CallToolParamsFor[map[string]any]{
 		Name: "unsupported_tool",
@@ -36,7 +36,7 @@ func main() {
 
 	// Initialize database
 	log.Write(plog.SERVER, "setting up prompts db")
-	db, err := promptsdb.New(promptsdb.FILE_SYSTEM_PROVIDER, config.Storage, config.LogFile)
+	db, err := promptsdb.New(config.Storage.Provider, config.Storage, config.LogFile)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to initialize prompts db connection: %s", err)
 		os.Exit(-1)